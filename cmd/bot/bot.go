@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/client"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+)
+
+// difficulty is how quickly and carefully a bot plays.
+type difficulty struct {
+	// think is how often the bot makes a move of its own
+	think time.Duration
+	// reserve is the balance the bot keeps back when spawning
+	reserve int
+	// react is whether the bot answers enemy moves as they happen, rather
+	// than waiting for its next think
+	react bool
+}
+
+var difficulties = map[string]difficulty{
+	"easy":   {think: 8 * time.Second, reserve: 6, react: false},
+	"normal": {think: 4 * time.Second, reserve: 2, react: true},
+	"hard":   {think: 2 * time.Second, reserve: 0, react: true},
+}
+
+// bot plays a game on its own through a client session, asking its strategy
+// what to do.
+type bot struct {
+	name     string
+	session  *client.Session
+	strategy strategy
+	diff     difficulty
+
+	// mu guards session, which is set once joined, and rng and enemies,
+	// which are used from the think loop and the subscription handlers
+	mu  sync.Mutex
+	rng *rand.Rand
+	// enemies is where other players' units were last seen arriving
+	enemies map[gamelogic.Location]string
+}
+
+func newBot(name string, strat strategy, diff difficulty, seed int64) *bot {
+	return &bot{
+		name:     name,
+		strategy: strat,
+		diff:     diff,
+		rng:      rand.New(rand.NewSource(seed)),
+		enemies:  map[gamelogic.Location]string{},
+	}
+}
+
+func (b *bot) hooks() client.Hooks {
+	return client.Hooks{
+		OnMove:      b.onMove,
+		OnWarResult: b.onWarResult,
+	}
+}
+
+// run makes the bot think until stop is closed, the game is over or the bot
+// is kicked.
+func (b *bot) run(stop <-chan struct{}) {
+	b.mu.Lock()
+	session := b.session
+	b.mu.Unlock()
+	ticker := time.NewTicker(b.diff.think)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if session.State.IsGameOver() {
+				b.logf("the game is over")
+				return
+			}
+			b.mu.Lock()
+			orders := b.strategy.act(b)
+			b.mu.Unlock()
+			b.carryOut(session, orders)
+		case <-session.Kicked():
+			b.logf("kicked from the game")
+			return
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (b *bot) onMove(move gamelogic.ArmyMove, outcome gamelogic.MoveOutcome) {
+	if !move.Arrival {
+		return
+	}
+	b.mu.Lock()
+	b.enemies[move.ToLocation] = move.Player.Username
+	session := b.session
+	if session == nil || !b.diff.react {
+		// still joining, or waiting for the next think
+		b.mu.Unlock()
+		return
+	}
+	orders := b.strategy.seen(b, move)
+	b.mu.Unlock()
+	b.carryOut(session, orders)
+}
+
+func (b *bot) onWarResult(result gamelogic.WarResult) {
+	if result.Winner != b.name && result.Loser != b.name {
+		return
+	}
+	b.mu.Lock()
+	if result.Winner == b.name && !result.Draw {
+		delete(b.enemies, result.Location)
+	}
+	session := b.session
+	if session == nil {
+		// still joining
+		b.mu.Unlock()
+		return
+	}
+	orders := b.strategy.fought(b, result)
+	b.mu.Unlock()
+	b.carryOut(session, orders)
+}
+
+// carryOut sends orders through session right away, or queues and submits
+// them in a turn-based game. It's called without b.mu held.
+func (b *bot) carryOut(session *client.Session, orders [][]string) {
+	if len(orders) == 0 || session.State.IsGameOver() {
+		return
+	}
+	gs := session.State
+	for _, words := range orders {
+		b.logf("%v", words)
+		var err error
+		if gs.IsTurnBased() {
+			err = gs.QueueOrder(words)
+		} else {
+			err = session.Order(words)
+		}
+		if err != nil {
+			b.logf("error in %s order: %v", words[0], err)
+		}
+	}
+	if gs.IsTurnBased() {
+		err := session.Submit()
+		if err != nil {
+			b.logf("error submitting orders: %v", err)
+		}
+	}
+}
+
+// logf reports what the bot is doing on stderr, so it can be followed even
+// with the game output hidden.
+func (b *bot) logf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "[%s] %s\n", b.name, fmt.Sprintf(format, args...))
+}
+
+// idleUnits are the bot's units that aren't in transit, by ID.
+func (b *bot) idleUnits() []gamelogic.Unit {
+	units := []gamelogic.Unit{}
+	for _, unit := range b.session.State.GetPlayerSnap().Units {
+		if !unit.InTransit() {
+			units = append(units, unit)
+		}
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i].ID < units[j].ID })
+	return units
+}
+
+// held counts the bot's units in each territory.
+func (b *bot) held() map[gamelogic.Location]int {
+	held := map[gamelogic.Location]int{}
+	for _, unit := range b.session.State.GetPlayerSnap().Units {
+		held[unit.Location]++
+	}
+	return held
+}
+
+// affordable returns the best rank the bot is willing to pay for, strongest
+// first unless cheapest is set.
+func (b *bot) affordable(cheapest bool) (gamelogic.UnitRank, bool) {
+	ranks := []gamelogic.UnitRank{gamelogic.RankArtillery, gamelogic.RankCavalry, gamelogic.RankInfantry}
+	if cheapest {
+		ranks = []gamelogic.UnitRank{gamelogic.RankInfantry}
+	}
+	balance := b.session.State.GetBalance()
+	for _, rank := range ranks {
+		if balance-gamelogic.RankCost(rank) >= b.diff.reserve {
+			return rank, true
+		}
+	}
+	return "", false
+}
+
+func (b *bot) pick(locations []gamelogic.Location) gamelogic.Location {
+	return locations[b.rng.Intn(len(locations))]
+}
+
+func spawnOrder(loc gamelogic.Location, rank gamelogic.UnitRank) []string {
+	return []string{"spawn", string(loc), string(rank)}
+}
+
+func moveOrder(loc gamelogic.Location, units []gamelogic.Unit) []string {
+	words := []string{"move", string(loc)}
+	for _, unit := range units {
+		words = append(words, fmt.Sprint(unit.ID))
+	}
+	return words
+}
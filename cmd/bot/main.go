@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/client"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/config"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+var (
+	botCount       = flag.Int("bots", 1, "number of bots to run")
+	username       = flag.String("username", "bot", "username of the bot, numbered when running more than one")
+	gameID         = flag.String("game", routing.DefaultGame, "game to join, created if it doesn't exist")
+	strategyName   = flag.String("strategy", "random", "random, aggressive, defensive, greedy or mixed")
+	difficultyName = flag.String("difficulty", "normal", "easy, normal or hard")
+	seed           = flag.Int64("seed", 0, "random seed, 0 picks one from the clock")
+	quiet          = flag.Bool("quiet", true, "hide the game output and only show what the bots do")
+)
+
+var configFlags = config.RegisterFlags(flag.CommandLine)
+
+func main() {
+	flag.Parse()
+	cfg, err := config.Load(configFlags)
+	if err != nil {
+		fmt.Printf("error loading config: %v\n", err)
+		return
+	}
+	fmt.Println("Starting Peril bots...")
+	diff, ok := difficulties[*difficultyName]
+	if !ok {
+		fmt.Printf("unknown difficulty: %s\n", *difficultyName)
+		return
+	}
+	if _, ok := strategies[*strategyName]; !ok && *strategyName != "mixed" {
+		fmt.Printf("unknown strategy: %s\n", *strategyName)
+		return
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	fmt.Printf("Using seed %d\n", *seed)
+
+	conn, err := cfg.Dial()
+	if err != nil {
+		fmt.Printf("unable to connect to AMQP server %s, %v\n", cfg.RedactedURL(), err)
+		return
+	}
+	defer conn.Close()
+
+	if *quiet {
+		devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		if err == nil {
+			os.Stdout = devNull
+		}
+	}
+
+	stop := make(chan struct{})
+	bots := []*bot{}
+	for i := 0; i < *botCount; i++ {
+		name := *username
+		if *botCount > 1 {
+			name = fmt.Sprintf("%s-%d", *username, i+1)
+		}
+		strat := *strategyName
+		if strat == "mixed" {
+			strat = strategyNames[i%len(strategyNames)]
+		}
+		b := newBot(name, strategies[strat](), diff, *seed+int64(i))
+		err := startBot(conn, cfg, b)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error starting %s: %v\n", name, err)
+			continue
+		}
+		b.logf("playing %s as %s on %s", *gameID, strat, *difficultyName)
+		bots = append(bots, b)
+		go b.run(stop)
+	}
+	if len(bots) == 0 {
+		return
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	<-interrupt
+	fmt.Fprintln(os.Stderr, "Stopping Peril bots...")
+	close(stop)
+	for _, b := range bots {
+		b.session.Close()
+	}
+}
+
+// startBot joins the bot to the game, creating the game if needed.
+func startBot(conn *amqp.Connection, cfg config.Config, b *bot) error {
+	lobby, err := client.NewLobby(conn, b.name)
+	if err != nil {
+		return err
+	}
+	info, err := lobby.Enter(routing.LobbyJoin, *gameID, false)
+	if err != nil && strings.Contains(err.Error(), "does not exist") {
+		info, err = lobby.Enter(routing.LobbyCreate, *gameID, false)
+	}
+	if err != nil {
+		return err
+	}
+	session, err := client.Join(cfg, b.name, info, b.hooks())
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	b.session = session
+	b.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+)
+
+// strategy decides a bot's orders. Every method is called with the bot's
+// lock held and returns the orders to carry out, as they'd be typed into the
+// client.
+type strategy interface {
+	// act is called every time the bot thinks.
+	act(b *bot) [][]string
+	// seen is called when another player's units arrive somewhere.
+	seen(b *bot, move gamelogic.ArmyMove) [][]string
+	// fought is called when a war the bot was in is over.
+	fought(b *bot, result gamelogic.WarResult) [][]string
+}
+
+var strategies = map[string]func() strategy{
+	"random":     func() strategy { return randomStrategy{} },
+	"aggressive": func() strategy { return aggressiveStrategy{} },
+	"defensive":  func() strategy { return defensiveStrategy{} },
+	"greedy":     func() strategy { return greedyStrategy{} },
+}
+
+// strategyNames is the order bots are given strategies in with -strategy mixed.
+var strategyNames = []string{"random", "aggressive", "defensive", "greedy"}
+
+// randomStrategy spawns and wanders without a plan.
+type randomStrategy struct{}
+
+func (randomStrategy) act(b *bot) [][]string {
+	units := b.idleUnits()
+	if len(units) == 0 || b.rng.Intn(2) == 0 {
+		rank, ok := b.affordable(b.rng.Intn(2) == 0)
+		if !ok {
+			return nil
+		}
+		return [][]string{spawnOrder(b.pick(gamelogic.Locations()), rank)}
+	}
+	unit := units[b.rng.Intn(len(units))]
+	to := b.pick(gamelogic.AdjacentLocations(unit.Location))
+	return [][]string{moveOrder(to, []gamelogic.Unit{unit})}
+}
+
+func (randomStrategy) seen(b *bot, move gamelogic.ArmyMove) [][]string {
+	return nil
+}
+
+func (randomStrategy) fought(b *bot, result gamelogic.WarResult) [][]string {
+	return nil
+}
+
+// aggressiveStrategy builds the strongest units it can and sends everything
+// at the last place it saw an enemy.
+type aggressiveStrategy struct{}
+
+func (s aggressiveStrategy) act(b *bot) [][]string {
+	target := b.pick(gamelogic.Locations())
+	if len(b.enemies) > 0 {
+		seen := []gamelogic.Location{}
+		for _, loc := range gamelogic.Locations() {
+			if _, ok := b.enemies[loc]; ok {
+				seen = append(seen, loc)
+			}
+		}
+		target = b.pick(seen)
+	}
+	return s.attack(b, target)
+}
+
+func (s aggressiveStrategy) seen(b *bot, move gamelogic.ArmyMove) [][]string {
+	return s.attack(b, move.ToLocation)
+}
+
+func (aggressiveStrategy) fought(b *bot, result gamelogic.WarResult) [][]string {
+	if result.Winner == b.name && !result.Draw {
+		return nil
+	}
+	// rebuild next door and try again
+	rank, ok := b.affordable(false)
+	if !ok {
+		return nil
+	}
+	return [][]string{spawnOrder(b.pick(gamelogic.AdjacentLocations(result.Location)), rank)}
+}
+
+func (aggressiveStrategy) attack(b *bot, target gamelogic.Location) [][]string {
+	orders := [][]string{}
+	if rank, ok := b.affordable(false); ok {
+		orders = append(orders, spawnOrder(b.pick(gamelogic.AdjacentLocations(target)), rank))
+	}
+	attackers := []gamelogic.Unit{}
+	for _, unit := range b.idleUnits() {
+		if unit.Location != target {
+			attackers = append(attackers, unit)
+		}
+	}
+	if len(attackers) > 0 {
+		orders = append(orders, moveOrder(target, attackers))
+	}
+	return orders
+}
+
+// defensiveStrategy never moves, and reinforces whatever is threatened.
+type defensiveStrategy struct{}
+
+func (defensiveStrategy) act(b *bot) [][]string {
+	held := b.held()
+	rank, ok := b.affordable(false)
+	if !ok {
+		return nil
+	}
+	if len(held) == 0 {
+		return [][]string{spawnOrder(b.pick(gamelogic.Locations()), rank)}
+	}
+	// shore up the weakest territory
+	weakest := gamelogic.Location("")
+	for _, loc := range gamelogic.Locations() {
+		n, ok := held[loc]
+		if ok && (weakest == "" || n < held[weakest]) {
+			weakest = loc
+		}
+	}
+	return [][]string{spawnOrder(weakest, rank)}
+}
+
+func (defensiveStrategy) seen(b *bot, move gamelogic.ArmyMove) [][]string {
+	if _, ok := b.held()[move.ToLocation]; !ok {
+		return nil
+	}
+	rank, ok := b.affordable(false)
+	if !ok {
+		return nil
+	}
+	return [][]string{spawnOrder(move.ToLocation, rank)}
+}
+
+func (defensiveStrategy) fought(b *bot, result gamelogic.WarResult) [][]string {
+	return nil
+}
+
+// greedyStrategy spreads out to hold as many territories as it can, since
+// each one pays income.
+type greedyStrategy struct{}
+
+func (greedyStrategy) act(b *bot) [][]string {
+	held := b.held()
+	unheld := []gamelogic.Location{}
+	for _, loc := range gamelogic.Locations() {
+		if _, ok := held[loc]; !ok {
+			unheld = append(unheld, loc)
+		}
+	}
+	if len(unheld) == 0 {
+		return nil
+	}
+
+	// spread units that are doubled up into empty territories next door
+	for _, unit := range b.idleUnits() {
+		if held[unit.Location] < 2 {
+			continue
+		}
+		for _, loc := range gamelogic.AdjacentLocations(unit.Location) {
+			if _, ok := held[loc]; !ok {
+				return [][]string{moveOrder(loc, []gamelogic.Unit{unit})}
+			}
+		}
+	}
+
+	rank, ok := b.affordable(true)
+	if !ok {
+		return nil
+	}
+	return [][]string{spawnOrder(b.pick(unheld), rank)}
+}
+
+func (greedyStrategy) seen(b *bot, move gamelogic.ArmyMove) [][]string {
+	return nil
+}
+
+func (greedyStrategy) fought(b *bot, result gamelogic.WarResult) [][]string {
+	if result.Winner == b.name && !result.Draw {
+		return nil
+	}
+	// take the territory back cheaply
+	rank, ok := b.affordable(true)
+	if !ok {
+		return nil
+	}
+	return [][]string{spawnOrder(result.Location, rank)}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/client"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+)
+
+var (
+	lobbyCommands = []string{"games", "create", "join", "help", "quit"}
+	gameCommands  = []string{
+		"spawn", "move", "status", "say", "whisper", "propose", "accept", "break",
+		"treaties", "orders", "submit", "spam", "leave", "quit", "help",
+	}
+)
+
+// completions are the words that can go where the last of words is. With no
+// session the player is in the lobby. players are the other players we know
+// of, for the commands that take a name.
+func completions(words []string, session *client.Session, players []string) []string {
+	if len(words) == 1 {
+		if session == nil {
+			return lobbyCommands
+		}
+		return gameCommands
+	}
+	arg := len(words) - 1
+	switch words[0] {
+	case "create":
+		if arg == 2 {
+			return []string{"fog"}
+		}
+	case "spawn":
+		switch arg {
+		case 1:
+			return locationNames()
+		case 2:
+			return rankNames()
+		}
+	case "move":
+		if arg == 1 {
+			return locationNames()
+		}
+		if session != nil {
+			return unitIDs(session.State.GetPlayerSnap())
+		}
+	case "propose", "break":
+		if arg == 1 {
+			return []string{"alliance", "ceasefire"}
+		}
+		if arg == 2 {
+			return players
+		}
+	case "whisper", "accept":
+		if arg == 1 {
+			return players
+		}
+	}
+	return nil
+}
+
+func locationNames() []string {
+	names := []string{}
+	for _, loc := range gamelogic.Locations() {
+		names = append(names, string(loc))
+	}
+	return names
+}
+
+func rankNames() []string {
+	names := []string{}
+	for _, rank := range gamelogic.Ranks() {
+		names = append(names, string(rank))
+	}
+	return names
+}
+
+func unitIDs(p gamelogic.Player) []string {
+	ids := []string{}
+	for _, unit := range sortedUnits(p) {
+		ids = append(ids, strconv.Itoa(unit.ID))
+	}
+	return ids
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/client"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+)
+
+// input is where the lobby and game REPLs get their commands from: the
+// player at the terminal, the TUI or a script.
+type input interface {
+	// next returns the next command, or false once there are none left.
+	next() ([]string, bool)
+	// errorf reports a command that failed.
+	errorf(format string, args ...any)
+	// hooks are passed on to the session when the player joins a game.
+	hooks() client.Hooks
+	// joined is called with the session once the player is in a game, and
+	// with nil when they leave it.
+	joined(session *client.Session)
+}
+
+// terminal reads commands typed by the player. It keeps track of the game
+// so the line editor can complete unit IDs.
+type terminal struct {
+	mu      sync.Mutex
+	session *client.Session
+}
+
+func (*terminal) next() ([]string, bool) {
+	return gamelogic.ReadInput()
+}
+
+func (*terminal) errorf(format string, args ...any) {
+	fmt.Printf(format+"\n", args...)
+}
+
+func (*terminal) hooks() client.Hooks {
+	return client.Hooks{}
+}
+
+func (t *terminal) joined(session *client.Session) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.session = session
+}
+
+func (t *terminal) complete(words []string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var players []string
+	if t.session != nil {
+		players = t.session.State.OtherPlayers()
+	}
+	return completions(words, t.session, players)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/client"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+// runLobby is the lobby REPL. It returns the game the player joined, or false
+// if they quit.
+func runLobby(lobby *client.Lobby, in input) (routing.GameInfo, bool) {
+	gamelogic.PrintLobbyHelp()
+	resp, err := lobby.Request(routing.LobbyList, "", false)
+	if err != nil {
+		in.errorf("error listing games: %v", err)
+	} else {
+		gamelogic.PrintGames(resp.Games)
+	}
+
+	for {
+		inputWords, ok := in.next()
+		if !ok {
+			return routing.GameInfo{}, false
+		}
+		if len(inputWords) == 0 {
+			continue
+		}
+		switch inputWords[0] {
+		case "games":
+			resp, err := lobby.Request(routing.LobbyList, "", false)
+			if err != nil {
+				in.errorf("error listing games: %v", err)
+				continue
+			}
+			gamelogic.PrintGames(resp.Games)
+		case "create":
+			if len(inputWords) != 2 && (len(inputWords) != 3 || inputWords[2] != "fog") {
+				in.errorf("usage: create <game> [fog]")
+				continue
+			}
+			info, err := lobby.Enter(routing.LobbyCreate, inputWords[1], len(inputWords) == 3)
+			if err != nil {
+				in.errorf("error in create command: %v", err)
+				continue
+			}
+			return info, true
+		case "join":
+			if len(inputWords) != 2 {
+				in.errorf("usage: join <game>")
+				continue
+			}
+			info, err := lobby.Enter(routing.LobbyJoin, inputWords[1], false)
+			if err != nil {
+				in.errorf("error in join command: %v", err)
+				continue
+			}
+			return info, true
+		case "help":
+			gamelogic.PrintLobbyHelp()
+		case "quit":
+			gamelogic.PrintQuit()
+			return routing.GameInfo{}, false
+		default:
+			in.errorf("unrecognized command")
+		}
+	}
+}
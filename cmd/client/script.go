@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/client"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+)
+
+// expectTimeout is how long expect waits when the script doesn't say.
+const expectTimeout = 10 * time.Second
+
+const assertUsage = "usage: assert status contains|lacks <text>, or assert units|balance <op> <number>"
+
+// script plays the client from a file, one command per line. Besides the
+// lobby and game commands it understands:
+//
+//	wait <duration>                  pause the script, e.g. wait 2s
+//	expect <event> [timeout]         wait for an event, e.g. expect move 5s
+//	assert status contains <text>    the status output must include text
+//	assert status lacks <text>       the status output must not include text
+//	assert units|balance <op> <n>    compare with ==, !=, <, <=, > or >=
+//
+// Blank lines and lines starting with # are skipped. The script stops at the
+// first command or check that fails.
+type script struct {
+	path  string
+	lines []string
+	// line is the number of the line last read.
+	line   int
+	events chan string
+	state  *gamelogic.GameState
+	// err is the first failure, which ends the script.
+	err error
+}
+
+func loadScript(path string) (*script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sc := &script{
+		path:   path,
+		lines:  strings.Split(string(data), "\n"),
+		events: make(chan string, 1024),
+	}
+	return sc, nil
+}
+
+func (sc *script) next() ([]string, bool) {
+	for sc.err == nil && sc.line < len(sc.lines) {
+		sc.line++
+		words := strings.Fields(sc.lines[sc.line-1])
+		if len(words) == 0 || strings.HasPrefix(words[0], "#") {
+			continue
+		}
+		fmt.Printf("> %s\n", strings.Join(words, " "))
+
+		var err error
+		switch words[0] {
+		case "wait":
+			err = sc.wait(words)
+		case "expect":
+			err = sc.expect(words)
+		case "assert":
+			err = sc.assert(words)
+		default:
+			return words, true
+		}
+		if err != nil {
+			sc.errorf("%v", err)
+		}
+	}
+	return nil, false
+}
+
+func (sc *script) errorf(format string, args ...any) {
+	err := fmt.Errorf(format, args...)
+	fmt.Println(err)
+	if sc.err == nil {
+		sc.err = fmt.Errorf("%s:%d: %w", sc.path, sc.line, err)
+	}
+}
+
+// hooks forgets the events of any earlier game and collects the new ones
+// for expect.
+func (sc *script) hooks() client.Hooks {
+	for len(sc.events) > 0 {
+		<-sc.events
+	}
+	return client.Hooks{
+		OnEvent: func(event string) {
+			select {
+			case sc.events <- event:
+			default:
+			}
+		},
+	}
+}
+
+func (sc *script) joined(session *client.Session) {
+	sc.state = nil
+	if session != nil {
+		sc.state = session.State
+	}
+}
+
+func (sc *script) wait(words []string) error {
+	if len(words) != 2 {
+		return errors.New("usage: wait <duration>")
+	}
+	d, err := time.ParseDuration(words[1])
+	if err != nil {
+		return fmt.Errorf("can't parse duration %s: %w", words[1], err)
+	}
+	time.Sleep(d)
+	return nil
+}
+
+// expect waits for the next event with the given name. Other events that
+// come in first are skipped.
+func (sc *script) expect(words []string) error {
+	if len(words) != 2 && len(words) != 3 {
+		return errors.New("usage: expect <event> [timeout]")
+	}
+	event := words[1]
+	if !slices.Contains(client.Events, event) {
+		return fmt.Errorf("unknown event %s, expected one of %s", event, strings.Join(client.Events, ", "))
+	}
+	timeout := expectTimeout
+	if len(words) == 3 {
+		d, err := time.ParseDuration(words[2])
+		if err != nil {
+			return fmt.Errorf("can't parse timeout %s: %w", words[2], err)
+		}
+		timeout = d
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case got := <-sc.events:
+			if got == event {
+				return nil
+			}
+		case <-timer.C:
+			return fmt.Errorf("no %s event within %v", event, timeout)
+		}
+	}
+}
+
+func (sc *script) assert(words []string) error {
+	if sc.state == nil {
+		return errors.New("assert needs a game, join one first")
+	}
+	if len(words) < 4 {
+		return errors.New(assertUsage)
+	}
+	switch words[1] {
+	case "status":
+		if words[2] != "contains" && words[2] != "lacks" {
+			return errors.New(assertUsage)
+		}
+		text := strings.Join(words[3:], " ")
+		var b strings.Builder
+		sc.state.WriteStatus(&b)
+		found := strings.Contains(b.String(), text)
+		if words[2] == "contains" && !found {
+			return fmt.Errorf("assertion failed: status does not contain %q", text)
+		}
+		if words[2] == "lacks" && found {
+			return fmt.Errorf("assertion failed: status contains %q", text)
+		}
+		return nil
+	case "units", "balance":
+		if len(words) != 4 {
+			return errors.New(assertUsage)
+		}
+		want, err := strconv.Atoi(words[3])
+		if err != nil {
+			return fmt.Errorf("can't convert %s to number: %w", words[3], err)
+		}
+		got := sc.state.GetBalance()
+		if words[1] == "units" {
+			got = len(sc.state.GetPlayerSnap().Units)
+		}
+		ok, err := compare(got, words[2], want)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("assertion failed: %s is %d, expected %s %d", words[1], got, words[2], want)
+		}
+		return nil
+	default:
+		return errors.New(assertUsage)
+	}
+}
+
+func compare(got int, op string, want int) (bool, error) {
+	switch op {
+	case "==":
+		return got == want, nil
+	case "!=":
+		return got != want, nil
+	case "<":
+		return got < want, nil
+	case "<=":
+		return got <= want, nil
+	case ">":
+		return got > want, nil
+	case ">=":
+		return got >= want, nil
+	default:
+		return false, fmt.Errorf("unknown comparison %s", op)
+	}
+}
@@ -0,0 +1,514 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/client"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+)
+
+const (
+	// feedSize is how many lines of the event feed are kept for scrolling.
+	feedSize = 1000
+	// exitLines is how much of the feed is printed when the TUI closes, so
+	// the reason the client stopped isn't lost with the screen.
+	exitLines = 10
+	// queuedLines is how many entered commands can wait to be carried out
+	// before more are turned away.
+	queuedLines = 16
+)
+
+var (
+	styleDefault = tcell.StyleDefault
+	styleHeader  = tcell.StyleDefault.Reverse(true)
+	styleTitle   = tcell.StyleDefault.Bold(true)
+)
+
+// tui is the full-screen client. It shows the map, your army and a feed of
+// everything the game prints, and reads commands from a line editor with
+// history and tab completion.
+//
+// The game code prints straight to stdout, so while the TUI is open stdout
+// is a pipe into the feed. tcell draws on the terminal itself.
+type tui struct {
+	screen tcell.Screen
+	stdout *os.File
+	pipe   *os.File
+	// output is closed once everything printed has made it to the feed.
+	output chan struct{}
+	done   chan struct{}
+
+	// lines are the commands entered on the command line.
+	lines chan []string
+
+	mu      sync.Mutex
+	session *client.Session
+	// enemies are the units of other players as last seen in their moves.
+	enemies map[string]map[int]gamelogic.Unit
+	feed    []string
+	scroll  int
+	line    []rune
+	cursor  int
+	history []string
+	histPos int
+}
+
+func newTUI() (*tui, error) {
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return nil, err
+	}
+	err = screen.Init()
+	if err != nil {
+		return nil, err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		screen.Fini()
+		return nil, err
+	}
+
+	t := &tui{
+		screen:  screen,
+		stdout:  os.Stdout,
+		pipe:    w,
+		output:  make(chan struct{}),
+		done:    make(chan struct{}),
+		lines:   make(chan []string, queuedLines),
+		enemies: map[string]map[int]gamelogic.Unit{},
+	}
+	os.Stdout = w
+	go t.readOutput(r)
+	go t.pollKeys()
+	go t.tick()
+	t.draw()
+	return t, nil
+}
+
+// close puts the terminal back the way it was and prints the end of the
+// feed.
+func (t *tui) close() {
+	close(t.done)
+	os.Stdout = t.stdout
+	t.pipe.Close()
+	<-t.output
+	t.screen.Fini()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	start := max(len(t.feed)-exitLines, 0)
+	for _, line := range t.feed[start:] {
+		fmt.Println(line)
+	}
+}
+
+func (t *tui) next() ([]string, bool) {
+	return <-t.lines, true
+}
+
+func (t *tui) errorf(format string, args ...any) {
+	fmt.Printf(format+"\n", args...)
+}
+
+// hooks keep track of where the other players' units were last seen.
+func (t *tui) hooks() client.Hooks {
+	return client.Hooks{
+		OnMove: func(move gamelogic.ArmyMove, _ gamelogic.MoveOutcome) {
+			units := map[int]gamelogic.Unit{}
+			for id, unit := range move.Player.Units {
+				units[id] = unit
+			}
+			t.mu.Lock()
+			t.enemies[move.Player.Username] = units
+			t.mu.Unlock()
+		},
+		OnWarResult: func(result gamelogic.WarResult) {
+			if result.Draw {
+				return
+			}
+			t.mu.Lock()
+			for id, unit := range t.enemies[result.Loser] {
+				if unit.Location == result.Location {
+					delete(t.enemies[result.Loser], id)
+				}
+			}
+			t.mu.Unlock()
+		},
+		OnEvent: func(string) {
+			t.draw()
+		},
+	}
+}
+
+func (t *tui) joined(session *client.Session) {
+	t.mu.Lock()
+	t.session = session
+	t.enemies = map[string]map[int]gamelogic.Unit{}
+	t.mu.Unlock()
+	t.draw()
+}
+
+// askUsername is ClientWelcome on the command line.
+func (t *tui) askUsername() (string, error) {
+	fmt.Println("Welcome to the Peril client!")
+	fmt.Println("Please enter your username:")
+	words, _ := t.next()
+	if len(words) == 0 {
+		return "", fmt.Errorf("you must enter a username. goodbye")
+	}
+	fmt.Printf("Welcome, %s!\n", words[0])
+	return words[0], nil
+}
+
+// readOutput moves everything printed to stdout into the feed. The REPL
+// prompts are dropped, there's a command line for that.
+func (t *tui) readOutput(r *os.File) {
+	defer close(t.output)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		for strings.HasPrefix(line, "> ") {
+			line = strings.TrimPrefix(line, "> ")
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		t.mu.Lock()
+		t.addFeed(line)
+		t.mu.Unlock()
+		t.draw()
+	}
+}
+
+func (t *tui) addFeed(line string) {
+	t.feed = append(t.feed, line)
+	if len(t.feed) > feedSize {
+		t.feed = t.feed[len(t.feed)-feedSize:]
+	}
+}
+
+// tick redraws every second to keep balances and travel times current.
+func (t *tui) tick() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.draw()
+		case <-t.done:
+			return
+		}
+	}
+}
+
+func (t *tui) pollKeys() {
+	for {
+		ev := t.screen.PollEvent()
+		if ev == nil {
+			return
+		}
+		switch ev := ev.(type) {
+		case *tcell.EventResize:
+			t.screen.Sync()
+			t.draw()
+		case *tcell.EventKey:
+			t.key(ev)
+			t.draw()
+		}
+	}
+}
+
+// enter hands a command over to next without holding up the keys: while the
+// game is busy the command waits, and once too many are waiting it is
+// dropped.
+func (t *tui) enter(words []string) {
+	select {
+	case t.lines <- words:
+	default:
+		t.mu.Lock()
+		t.addFeed("Too many commands waiting, try again in a moment.")
+		t.mu.Unlock()
+	}
+}
+
+func (t *tui) key(ev *tcell.EventKey) {
+	t.mu.Lock()
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		text := strings.TrimSpace(string(t.line))
+		t.line = nil
+		t.cursor = 0
+		t.scroll = 0
+		if text != "" {
+			t.history = append(t.history, text)
+		}
+		t.histPos = len(t.history)
+		if text != "" {
+			t.addFeed("> " + text)
+		}
+		t.mu.Unlock()
+		t.enter(strings.Fields(text))
+		return
+	case tcell.KeyCtrlC, tcell.KeyCtrlD:
+		t.mu.Unlock()
+		t.enter([]string{"quit"})
+		return
+	case tcell.KeyTab:
+		t.complete()
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if t.cursor > 0 {
+			t.line = append(t.line[:t.cursor-1], t.line[t.cursor:]...)
+			t.cursor--
+		}
+	case tcell.KeyDelete:
+		if t.cursor < len(t.line) {
+			t.line = append(t.line[:t.cursor], t.line[t.cursor+1:]...)
+		}
+	case tcell.KeyLeft, tcell.KeyCtrlB:
+		t.cursor = max(t.cursor-1, 0)
+	case tcell.KeyRight, tcell.KeyCtrlF:
+		t.cursor = min(t.cursor+1, len(t.line))
+	case tcell.KeyHome, tcell.KeyCtrlA:
+		t.cursor = 0
+	case tcell.KeyEnd, tcell.KeyCtrlE:
+		t.cursor = len(t.line)
+	case tcell.KeyCtrlU:
+		t.line = t.line[t.cursor:]
+		t.cursor = 0
+	case tcell.KeyCtrlK:
+		t.line = t.line[:t.cursor]
+	case tcell.KeyUp, tcell.KeyCtrlP:
+		if t.histPos > 0 {
+			t.histPos--
+			t.line = []rune(t.history[t.histPos])
+			t.cursor = len(t.line)
+		}
+	case tcell.KeyDown, tcell.KeyCtrlN:
+		if t.histPos < len(t.history) {
+			t.histPos++
+			t.line = nil
+			if t.histPos < len(t.history) {
+				t.line = []rune(t.history[t.histPos])
+			}
+			t.cursor = len(t.line)
+		}
+	case tcell.KeyPgUp:
+		t.scroll = min(t.scroll+10, max(len(t.feed)-1, 0))
+	case tcell.KeyPgDn:
+		t.scroll = max(t.scroll-10, 0)
+	case tcell.KeyRune:
+		t.line = append(t.line[:t.cursor], append([]rune{ev.Rune()}, t.line[t.cursor:]...)...)
+		t.cursor++
+	}
+	t.mu.Unlock()
+}
+
+// complete finishes the word before the cursor. If there's more than one
+// way to go it lists the choices in the feed.
+func (t *tui) complete() {
+	before := string(t.line[:t.cursor])
+	words := strings.Fields(before)
+	if len(words) == 0 || strings.HasSuffix(before, " ") {
+		words = append(words, "")
+	}
+	word := words[len(words)-1]
+
+	matches := []string{}
+	for _, c := range completions(words, t.session, t.enemyNames()) {
+		if strings.HasPrefix(c, word) {
+			matches = append(matches, c)
+		}
+	}
+	if len(matches) == 0 {
+		return
+	}
+
+	prefix := matches[0]
+	for _, m := range matches[1:] {
+		for !strings.HasPrefix(m, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	insert := []rune(strings.TrimPrefix(prefix, word))
+	if len(matches) == 1 {
+		insert = append(insert, ' ')
+	}
+	t.line = append(t.line[:t.cursor], append(insert, t.line[t.cursor:]...)...)
+	t.cursor += len(insert)
+	if len(matches) > 1 && len(insert) == 0 {
+		t.addFeed(strings.Join(matches, "  "))
+	}
+}
+
+func (t *tui) enemyNames() []string {
+	names := []string{}
+	for name := range t.enemies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedUnits(p gamelogic.Player) []gamelogic.Unit {
+	units := []gamelogic.Unit{}
+	for _, unit := range p.Units {
+		units = append(units, unit)
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i].ID < units[j].ID })
+	return units
+}
+
+// draw lays out the screen:
+//
+//	Map                       | Army
+//	one row per territory     | one row per unit
+//	Events
+//	the feed, newest at the bottom
+//	status bar
+//	> command line
+func (t *tui) draw() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.screen
+	s.Clear()
+	w, h := s.Size()
+	locations := gamelogic.Locations()
+	top := len(locations) + 1
+	half := w / 2
+
+	var me gamelogic.Player
+	if t.session != nil {
+		me = t.session.State.GetPlayerSnap()
+	}
+
+	drawText(s, 0, 0, half, styleHeader, pad(" Map", half))
+	for i, loc := range locations {
+		drawText(s, 0, i+1, half, styleDefault, t.territory(loc, me))
+	}
+
+	drawText(s, half, 0, w, styleHeader, pad(" Army", w-half))
+	units := sortedUnits(me)
+	for i, unit := range units {
+		if i == top-2 && len(units) > top-1 {
+			drawText(s, half, i+1, w, styleDefault, fmt.Sprintf(" ... and %d more", len(units)-i))
+			break
+		}
+		drawText(s, half, i+1, w, styleDefault, " "+describeUnit(unit))
+	}
+
+	drawText(s, 0, top, w, styleHeader, pad(" Events", w))
+	t.drawFeed(top+1, h-2, w)
+
+	drawText(s, 0, h-2, w, styleHeader, pad(" "+t.statusLine(), w))
+	prompt := "> " + string(t.line)
+	drawText(s, 0, h-1, w, styleDefault, prompt)
+	s.ShowCursor(min(2+t.cursor, w-1), h-1)
+	s.Show()
+}
+
+// territory is a map row: your units there, then everyone else's.
+func (t *tui) territory(loc gamelogic.Location, me gamelogic.Player) string {
+	row := fmt.Sprintf(" %-11s", loc)
+	if n := unitsIn(me, loc); n > 0 {
+		row += fmt.Sprintf(" you %d", n)
+	}
+	for _, name := range t.enemyNames() {
+		p := gamelogic.Player{Username: name, Units: t.enemies[name]}
+		if n := unitsIn(p, loc); n > 0 {
+			row += fmt.Sprintf("  %s %d", name, n)
+		}
+	}
+	return row
+}
+
+func unitsIn(p gamelogic.Player, loc gamelogic.Location) int {
+	n := 0
+	for _, unit := range p.Units {
+		if unit.Location == loc {
+			n++
+		}
+	}
+	return n
+}
+
+func describeUnit(unit gamelogic.Unit) string {
+	desc := fmt.Sprintf("%d: %s in %s, veterancy %d", unit.ID, unit.Rank, unit.Location, unit.Veterancy)
+	if unit.InTransit() {
+		desc += fmt.Sprintf(", to %s in %v", unit.Destination, unit.Remaining)
+	}
+	return desc
+}
+
+func (t *tui) statusLine() string {
+	if t.session == nil {
+		return "In the lobby"
+	}
+	gs := t.session.State
+	status := fmt.Sprintf("%s in %s | balance %d (+%d)", gs.GetUsername(), t.session.Game, gs.GetBalance(), gs.GetIncome())
+	switch {
+	case gs.IsGameOver():
+		status += " | game over"
+	case gs.IsPaused():
+		status += " | paused"
+	}
+	if t.scroll > 0 {
+		status += fmt.Sprintf(" | scrolled back %d", t.scroll)
+	}
+	return status
+}
+
+// drawFeed fills rows from y0 up to y1 with the feed, wrapped to width w.
+func (t *tui) drawFeed(y0, y1, w int) {
+	if w <= 0 {
+		return
+	}
+	rows := []string{}
+	for i := len(t.feed) - 1 - t.scroll; i >= 0 && len(rows) < y1-y0; i-- {
+		line := []rune(t.feed[i])
+		wrapped := []string{}
+		for len(line) > w {
+			wrapped = append(wrapped, string(line[:w]))
+			line = line[w:]
+		}
+		wrapped = append(wrapped, string(line))
+		for j := len(wrapped) - 1; j >= 0; j-- {
+			rows = append(rows, wrapped[j])
+		}
+	}
+	for i, row := range rows {
+		y := y1 - 1 - i
+		if y < y0 {
+			break
+		}
+		style := styleDefault
+		if strings.HasPrefix(row, "==== ") {
+			style = styleTitle
+		}
+		drawText(t.screen, 0, y, w, style, row)
+	}
+}
+
+func drawText(s tcell.Screen, x, y, maxX int, style tcell.Style, text string) {
+	for _, r := range text {
+		if x >= maxX {
+			return
+		}
+		s.SetContent(x, y, r, nil, style)
+		x++
+	}
+}
+
+func pad(text string, width int) string {
+	if len(text) >= width {
+		return text
+	}
+	return text + strings.Repeat(" ", width-len(text))
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/config"
+)
+
+var (
+	httpAddr     = flag.String("http", ":8082", "address to accept WebSocket connections on")
+	allowOrigins = flag.String("allow-origin", "", "comma separated origins browsers may connect from, * for any (default same origin only)")
+)
+
+var configFlags = config.RegisterFlags(flag.CommandLine)
+
+// gateway lets programs that can't link the pubsub package play, by holding
+// a session for each of them. See protocol.go for what they send.
+type gateway struct {
+	cfg      config.Config
+	upgrader websocket.Upgrader
+
+	mu      sync.Mutex
+	players map[*player]struct{}
+	wg      sync.WaitGroup
+}
+
+func main() {
+	flag.Parse()
+	cfg, err := config.Load(configFlags)
+	if err != nil {
+		fmt.Printf("error loading config: %v\n", err)
+		return
+	}
+	cfg.Print()
+	fmt.Println("Starting Peril gateway...")
+
+	// Players connect to the broker when they log in, so check it's there
+	// before taking any.
+	conn, err := cfg.Dial()
+	if err != nil {
+		fmt.Printf("unable to connect to AMQP server %s, %v\n", cfg.RedactedURL(), err)
+		return
+	}
+	conn.Close()
+
+	gw := &gateway{
+		cfg:     cfg,
+		players: map[*player]struct{}{},
+	}
+	gw.upgrader.CheckOrigin = checkOrigin(*allowOrigins)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /", gw.handlePlay)
+	srv := &http.Server{
+		Addr:              *httpAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	fmt.Printf("Gateway listening on %s\n", *httpAddr)
+	err = srv.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf("error serving the gateway: %v\n", err)
+	}
+	gw.closeAll()
+	fmt.Println("Shutting down Peril gateway...")
+}
+
+func (gw *gateway) handlePlay(w http.ResponseWriter, r *http.Request) {
+	ws, err := gw.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already answered with an error
+		return
+	}
+	p := newPlayer(gw.cfg, ws)
+	gw.mu.Lock()
+	gw.players[p] = struct{}{}
+	gw.wg.Add(1)
+	gw.mu.Unlock()
+	go func() {
+		defer gw.wg.Done()
+		p.serve()
+		gw.mu.Lock()
+		delete(gw.players, p)
+		gw.mu.Unlock()
+	}()
+}
+
+// closeAll disconnects everyone, so they leave their games, and waits for
+// them to be gone.
+func (gw *gateway) closeAll() {
+	gw.mu.Lock()
+	for p := range gw.players {
+		p.ws.Close()
+	}
+	gw.mu.Unlock()
+	gw.wg.Wait()
+}
+
+// checkOrigin lets browsers connect from the allowed origins. Programs
+// other than browsers don't send an origin and are always let in.
+func checkOrigin(allowed string) func(*http.Request) bool {
+	if allowed == "" {
+		return nil
+	}
+	origins := map[string]bool{}
+	for _, origin := range strings.Split(allowed, ",") {
+		origins[strings.TrimSpace(origin)] = true
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || origins["*"] || origins[origin]
+	}
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/client"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/config"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+const (
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = pongWait * 9 / 10
+	// maxMessage is the largest request a player can send, in bytes.
+	maxMessage = 4096
+)
+
+var errNotInGame = errors.New("you are not in a game")
+
+// player is one WebSocket connection. It plays like the Go client does: a
+// lobby once the username is known, and a session while in a game.
+type player struct {
+	cfg config.Config
+	ws  *websocket.Conn
+	// writeMu serializes writes, which come from the read loop and from the
+	// session's subscriptions.
+	writeMu sync.Mutex
+
+	username string
+	conn     *amqp.Connection
+	lobby    *client.Lobby
+	// guest lists games for a player who hasn't logged in yet.
+	guest *client.Lobby
+
+	mu      sync.Mutex
+	session *client.Session
+	// left is closed when the player leaves the session's game.
+	left chan struct{}
+}
+
+func newPlayer(cfg config.Config, ws *websocket.Conn) *player {
+	return &player{cfg: cfg, ws: ws}
+}
+
+// serve handles requests until the connection closes, then takes the player
+// out of their game.
+func (p *player) serve() {
+	defer p.close()
+	done := make(chan struct{})
+	defer close(done)
+	go p.ping(done)
+
+	p.ws.SetReadLimit(maxMessage)
+	p.ws.SetReadDeadline(time.Now().Add(pongWait))
+	p.ws.SetPongHandler(func(string) error {
+		return p.ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	for {
+		_, data, err := p.ws.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				fmt.Printf("error reading from %s: %v\n", p.ws.RemoteAddr(), err)
+			}
+			return
+		}
+		var req request
+		err = json.Unmarshal(data, &req)
+		if err != nil {
+			p.send(reply{Type: msgError, Error: fmt.Sprintf("invalid request: %v", err)})
+			continue
+		}
+		p.send(p.handle(req))
+	}
+}
+
+func (p *player) handle(req request) reply {
+	var resp reply
+	var err error
+	switch req.Type {
+	case msgGames:
+		resp, err = p.games(req)
+	case msgJoin:
+		resp, err = p.join(req)
+	case msgSpawn, msgMove, msgSubmit:
+		resp, err = p.order(req)
+	case msgStatus:
+		s := p.current()
+		if s == nil {
+			err = errNotInGame
+			break
+		}
+		resp = reply{Status: newStatus(s.Game, s.State)}
+	case msgLeave:
+		err = p.leave()
+	default:
+		err = fmt.Errorf("unknown request type: %q", req.Type)
+	}
+	if err != nil {
+		return reply{Type: msgError, ID: req.ID, Error: err.Error()}
+	}
+	resp.Type = msgOK
+	resp.ID = req.ID
+	return resp
+}
+
+// login connects the player to the lobby the first time they give their
+// name.
+func (p *player) login(username string) error {
+	if p.username != "" {
+		if username != "" && username != p.username {
+			return fmt.Errorf("you are already playing as %s", p.username)
+		}
+		return nil
+	}
+	err := routing.ValidateUsername(username)
+	if err != nil {
+		return err
+	}
+	conn, err := p.connect()
+	if err != nil {
+		return err
+	}
+	lobby, err := client.NewLobby(conn, username)
+	if err != nil {
+		return err
+	}
+	p.username = username
+	p.lobby = lobby
+	fmt.Printf("%s logged in from %s\n", username, p.ws.RemoteAddr())
+	return nil
+}
+
+// connect dials the broker the first time the player needs it.
+func (p *player) connect() (*amqp.Connection, error) {
+	if p.conn != nil {
+		return p.conn, nil
+	}
+	conn, err := p.cfg.Dial()
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to AMQP server: %w", err)
+	}
+	p.conn = conn
+	return conn, nil
+}
+
+// games lists the games. It works without logging in: until the player
+// gives a name they ask through a guest lobby.
+func (p *player) games(req request) (reply, error) {
+	if req.Username != "" {
+		err := p.login(req.Username)
+		if err != nil {
+			return reply{}, err
+		}
+	}
+	lobby, err := p.browse()
+	if err != nil {
+		return reply{}, err
+	}
+	resp, err := lobby.Request(routing.LobbyList, "", false)
+	if err != nil {
+		return reply{}, err
+	}
+	return reply{Games: newGameInfos(resp.Games)}, nil
+}
+
+// browse returns the lobby to list games through: the player's own once
+// they've logged in, a guest one until then.
+func (p *player) browse() (*client.Lobby, error) {
+	if p.lobby != nil {
+		return p.lobby, nil
+	}
+	if p.guest != nil {
+		return p.guest, nil
+	}
+	conn, err := p.connect()
+	if err != nil {
+		return nil, err
+	}
+	guest, err := client.NewLobby(conn, fmt.Sprintf("guest-%d", rand.Int63()))
+	if err != nil {
+		return nil, err
+	}
+	p.guest = guest
+	return guest, nil
+}
+
+func (p *player) join(req request) (reply, error) {
+	err := p.login(req.Username)
+	if err != nil {
+		return reply{}, err
+	}
+	if s := p.current(); s != nil {
+		return reply{}, fmt.Errorf("you are already in game %s, leave it first", s.Game)
+	}
+	game := req.Game
+	if game == "" {
+		game = routing.DefaultGame
+	}
+	action := routing.LobbyJoin
+	if req.Create {
+		action = routing.LobbyCreate
+	}
+	info, err := p.lobby.Enter(action, game, req.Fog)
+	if err != nil {
+		return reply{}, err
+	}
+	session, err := client.Join(p.cfg, p.username, info, p.hooks())
+	if err != nil {
+		p.leaveLobby(game)
+		return reply{}, err
+	}
+
+	left := make(chan struct{})
+	p.mu.Lock()
+	p.session = session
+	p.left = left
+	p.mu.Unlock()
+	go p.watchKicked(session, left)
+	fmt.Printf("%s joined game %s\n", p.username, game)
+	return reply{Status: newStatus(session.Game, session.State)}, nil
+}
+
+// order turns spawn, move and submit into the words the REPL commands take,
+// and carries them out like the client does.
+func (p *player) order(req request) (reply, error) {
+	s := p.current()
+	if s == nil {
+		return reply{}, errNotInGame
+	}
+	gs := s.State
+	if gs.IsGameOver() {
+		return reply{}, errors.New("the game is over, only status and leave are available")
+	}
+
+	var err error
+	switch req.Type {
+	case msgSubmit:
+		err = s.Submit()
+	case msgSpawn:
+		err = p.carryOut(s, []string{"spawn", req.Location, req.Rank})
+	case msgMove:
+		words := []string{"move", req.Location}
+		for _, id := range req.Units {
+			words = append(words, strconv.Itoa(id))
+		}
+		err = p.carryOut(s, words)
+	}
+	if err != nil {
+		return reply{}, err
+	}
+	return reply{Status: newStatus(s.Game, gs)}, nil
+}
+
+// carryOut queues the order in a turn-based game and sends it right away
+// otherwise.
+func (p *player) carryOut(s *client.Session, words []string) error {
+	if s.State.IsTurnBased() {
+		return s.State.QueueOrder(words)
+	}
+	return s.Order(words)
+}
+
+func (p *player) leave() error {
+	s := p.takeSession()
+	if s == nil {
+		return errNotInGame
+	}
+	p.leaveGame(s)
+	return nil
+}
+
+// watchKicked takes the player back to the lobby when an admin kicks them.
+// The player hears about it through the kicked event.
+func (p *player) watchKicked(s *client.Session, left <-chan struct{}) {
+	select {
+	case <-s.Kicked():
+		p.mu.Lock()
+		if p.session != s {
+			p.mu.Unlock()
+			return
+		}
+		p.session = nil
+		close(p.left)
+		p.mu.Unlock()
+		fmt.Printf("%s was kicked from game %s\n", p.username, s.Game)
+		p.leaveGame(s)
+	case <-left:
+	}
+}
+
+func (p *player) current() *client.Session {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.session
+}
+
+// takeSession takes the session away from the player so it can be closed.
+// Only the caller that gets it may close it.
+func (p *player) takeSession() *client.Session {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.session
+	if s != nil {
+		p.session = nil
+		close(p.left)
+	}
+	return s
+}
+
+func (p *player) leaveGame(s *client.Session) {
+	s.Close()
+	p.leaveLobby(s.Game)
+	fmt.Printf("%s left game %s\n", p.username, s.Game)
+}
+
+func (p *player) leaveLobby(game string) {
+	_, err := p.lobby.Request(routing.LobbyLeave, game, false)
+	if err != nil {
+		fmt.Printf("error leaving game %s: %v\n", game, err)
+	}
+}
+
+func (p *player) close() {
+	if s := p.takeSession(); s != nil {
+		p.leaveGame(s)
+	}
+	if p.conn != nil {
+		p.conn.Close()
+	}
+	if p.username != "" {
+		fmt.Printf("%s logged out\n", p.username)
+	}
+	p.ws.Close()
+}
+
+// hooks push what the session hears about to the player. Moves, war
+// results, chat, diplomacy and admin messages come with the details, the
+// rest with just the event name.
+func (p *player) hooks() client.Hooks {
+	return client.Hooks{
+		OnMove: func(move gamelogic.ArmyMove, _ gamelogic.MoveOutcome) {
+			p.event(client.EventMove, reply{Move: newMoveInfo(move)})
+		},
+		OnWarResult: func(result gamelogic.WarResult) {
+			p.event(client.EventWarResult, reply{War: newWarInfo(result)})
+		},
+		OnChat: func(msg routing.ChatMessage) {
+			p.event(client.EventChat, reply{Chat: newChatInfo(msg)})
+		},
+		OnDiplomacy: func(du routing.DiplomacyUpdate) {
+			p.event(client.EventDiplomacy, reply{Diplomacy: newDiplomacyInfo(du)})
+		},
+		OnAdmin: func(msg routing.AdminMessage, outcome gamelogic.AdminOutcome) {
+			event := client.EventAdmin
+			if outcome == gamelogic.AdminOutcomeKicked {
+				event = client.EventKicked
+			}
+			p.event(event, reply{Admin: newAdminInfo(msg)})
+		},
+		OnEvent: func(event string) {
+			switch event {
+			case client.EventMove, client.EventWarResult, client.EventChat,
+				client.EventDiplomacy, client.EventAdmin, client.EventKicked:
+				// sent with their details above
+				return
+			}
+			p.event(event, reply{})
+		},
+	}
+}
+
+func (p *player) event(event string, r reply) {
+	r.Type = msgEvent
+	r.Event = event
+	if s := p.current(); s != nil {
+		r.Status = newStatus(s.Game, s.State)
+	}
+	p.send(r)
+}
+
+func (p *player) send(r reply) {
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	p.ws.SetWriteDeadline(time.Now().Add(writeWait))
+	err := p.ws.WriteJSON(r)
+	if err != nil {
+		fmt.Printf("error writing to %s: %v\n", p.ws.RemoteAddr(), err)
+	}
+}
+
+// ping keeps the connection alive, and notices when the player is gone.
+func (p *player) ping(done <-chan struct{}) {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err := p.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait))
+			if err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
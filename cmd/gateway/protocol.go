@@ -0,0 +1,308 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+// The gateway speaks JSON over WebSocket, one object per text message. Each
+// message has a "type". A request can carry an "id", which comes back in
+// the reply so bots can match them up.
+//
+// Requests, sent by the player:
+//
+//	{"type":"games"}                                  list the games, no login needed
+//	{"type":"join","username":"alice","game":"main"}  join a game
+//	{"type":"join","username":"alice","game":"mine","create":true,"fog":true}
+//	{"type":"spawn","location":"europe","rank":"infantry"}
+//	{"type":"move","location":"asia","units":[1,2]}
+//	{"type":"submit"}                                 send the orders queued this turn
+//	{"type":"status"}
+//	{"type":"leave"}                                  back to the lobby
+//
+// The username is set by the first join, or a games request carrying one,
+// and can't change on the same connection. In a turn-based game spawn and move are queued until submit.
+//
+// Replies, one for every request:
+//
+//	{"type":"ok","id":1,"status":{...}}
+//	{"type":"ok","id":2,"games":[...]}
+//	{"type":"error","id":3,"error":"you are not in a game"}
+//
+// Replies carry the player's status while they are in a game.
+//
+// Events, pushed while in a game:
+//
+//	{"type":"event","event":"move","move":{...},"status":{...}}
+//	{"type":"event","event":"war_result","war":{...},"status":{...}}
+//	{"type":"event","event":"pause","status":{...}}
+//	{"type":"event","event":"chat","chat":{"from":"bob","to":"","message":"hi"},"status":{...}}
+//	{"type":"event","event":"diplomacy","diplomacy":{"event":"proposed","treaty":{...},"treaties":[...]},"status":{...}}
+//	{"type":"event","event":"kicked","admin":{"kind":"kick","target":"alice","message":"..."}}
+//
+// The events are the ones the Go client hooks get: move, arrival, war,
+// war_result, pause, resume, turn, turn_resolved, economy, game_over,
+// joined, left, chat, diplomacy, admin and kicked. move, war_result, chat
+// and diplomacy come with their details, admin and kicked with the admin
+// message. After kicked the player is back in the lobby.
+const (
+	msgGames  = "games"
+	msgJoin   = "join"
+	msgSpawn  = "spawn"
+	msgMove   = "move"
+	msgSubmit = "submit"
+	msgStatus = "status"
+	msgLeave  = "leave"
+
+	msgOK    = "ok"
+	msgError = "error"
+	msgEvent = "event"
+)
+
+// request is any message from the player. Only the fields its type uses
+// are set.
+type request struct {
+	Type     string `json:"type"`
+	ID       any    `json:"id,omitempty"`
+	Username string `json:"username,omitempty"`
+	Game     string `json:"game,omitempty"`
+	Create   bool   `json:"create,omitempty"`
+	Fog      bool   `json:"fog,omitempty"`
+	Location string `json:"location,omitempty"`
+	Rank     string `json:"rank,omitempty"`
+	Units    []int  `json:"units,omitempty"`
+}
+
+// reply is any message to the player.
+type reply struct {
+	Type      string         `json:"type"`
+	ID        any            `json:"id,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	Event     string         `json:"event,omitempty"`
+	Games     []gameInfo     `json:"games,omitempty"`
+	Move      *moveInfo      `json:"move,omitempty"`
+	War       *warInfo       `json:"war,omitempty"`
+	Chat      *chatInfo      `json:"chat,omitempty"`
+	Diplomacy *diplomacyInfo `json:"diplomacy,omitempty"`
+	Admin     *adminInfo     `json:"admin,omitempty"`
+	Status    *status        `json:"status,omitempty"`
+}
+
+type gameInfo struct {
+	ID      string   `json:"id"`
+	Players []string `json:"players"`
+	Paused  bool     `json:"paused"`
+	Over    bool     `json:"over"`
+	Fog     bool     `json:"fog"`
+}
+
+type status struct {
+	Username  string     `json:"username"`
+	Game      string     `json:"game"`
+	Paused    bool       `json:"paused"`
+	Fog       bool       `json:"fog"`
+	TurnBased bool       `json:"turn_based"`
+	Balance   int        `json:"balance"`
+	Income    int        `json:"income"`
+	Units     []unitInfo `json:"units"`
+	Over      *overInfo  `json:"over,omitempty"`
+}
+
+type unitInfo struct {
+	ID          int    `json:"id"`
+	Rank        string `json:"rank"`
+	Location    string `json:"location"`
+	Veterancy   int    `json:"veterancy"`
+	Destination string `json:"destination,omitempty"`
+	// Remaining is how many seconds are left in transit.
+	Remaining float64 `json:"remaining,omitempty"`
+}
+
+type moveInfo struct {
+	Player  string     `json:"player"`
+	To      string     `json:"to"`
+	Arrival bool       `json:"arrival"`
+	Units   []unitInfo `json:"units"`
+}
+
+type warInfo struct {
+	Location string `json:"location"`
+	Winner   string `json:"winner"`
+	Loser    string `json:"loser"`
+	Draw     bool   `json:"draw"`
+}
+
+type chatInfo struct {
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+type diplomacyInfo struct {
+	Event    string       `json:"event"`
+	Treaty   treatyInfo   `json:"treaty"`
+	Reason   string       `json:"reason,omitempty"`
+	Treaties []treatyInfo `json:"treaties,omitempty"`
+}
+
+type treatyInfo struct {
+	Kind     string `json:"kind"`
+	Proposer string `json:"proposer"`
+	Partner  string `json:"partner"`
+	// Duration is how many seconds a ceasefire lasts.
+	Duration float64    `json:"duration,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+}
+
+type adminInfo struct {
+	Kind    string `json:"kind"`
+	Target  string `json:"target,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type overInfo struct {
+	Winner    string         `json:"winner,omitempty"`
+	Reason    string         `json:"reason"`
+	Standings []standingInfo `json:"standings"`
+}
+
+type standingInfo struct {
+	Username    string `json:"username"`
+	Score       int    `json:"score"`
+	Units       int    `json:"units"`
+	Territories int    `json:"territories"`
+}
+
+func newGameInfos(games []routing.GameInfo) []gameInfo {
+	infos := []gameInfo{}
+	for _, g := range games {
+		infos = append(infos, gameInfo{
+			ID:      g.ID,
+			Players: append([]string{}, g.Players...),
+			Paused:  g.Paused,
+			Over:    g.Over,
+			Fog:     g.Fog,
+		})
+	}
+	return infos
+}
+
+func newStatus(game string, gs *gamelogic.GameState) *status {
+	p := gs.GetPlayerSnap()
+	units := []gamelogic.Unit{}
+	for _, unit := range p.Units {
+		units = append(units, unit)
+	}
+	st := &status{
+		Username:  p.Username,
+		Game:      game,
+		Paused:    gs.IsPaused(),
+		Fog:       gs.IsFogOfWar(),
+		TurnBased: gs.IsTurnBased(),
+		Balance:   gs.GetBalance(),
+		Income:    gs.GetIncome(),
+		Units:     newUnitInfos(units),
+	}
+	if over := gs.GameOver(); over != nil {
+		st.Over = newOverInfo(*over)
+	}
+	return st
+}
+
+func newUnitInfos(units []gamelogic.Unit) []unitInfo {
+	infos := []unitInfo{}
+	for _, unit := range units {
+		infos = append(infos, unitInfo{
+			ID:          unit.ID,
+			Rank:        string(unit.Rank),
+			Location:    string(unit.Location),
+			Veterancy:   unit.Veterancy,
+			Destination: string(unit.Destination),
+			Remaining:   unit.Remaining.Seconds(),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+func newMoveInfo(move gamelogic.ArmyMove) *moveInfo {
+	return &moveInfo{
+		Player:  move.Player.Username,
+		To:      string(move.ToLocation),
+		Arrival: move.Arrival,
+		Units:   newUnitInfos(move.Units),
+	}
+}
+
+func newWarInfo(result gamelogic.WarResult) *warInfo {
+	return &warInfo{
+		Location: string(result.Location),
+		Winner:   result.Winner,
+		Loser:    result.Loser,
+		Draw:     result.Draw,
+	}
+}
+
+func newChatInfo(msg routing.ChatMessage) *chatInfo {
+	return &chatInfo{
+		From:    msg.From,
+		To:      msg.To,
+		Message: msg.Message,
+		Time:    msg.Time,
+	}
+}
+
+func newDiplomacyInfo(du routing.DiplomacyUpdate) *diplomacyInfo {
+	info := &diplomacyInfo{
+		Event:  du.Event,
+		Treaty: newTreatyInfo(du.Treaty),
+		Reason: du.Reason,
+	}
+	for _, t := range du.Treaties {
+		info.Treaties = append(info.Treaties, newTreatyInfo(t))
+	}
+	return info
+}
+
+func newTreatyInfo(t routing.Treaty) treatyInfo {
+	info := treatyInfo{
+		Kind:     t.Kind,
+		Proposer: t.Proposer,
+		Partner:  t.Partner,
+		Duration: t.Duration.Seconds(),
+	}
+	if !t.Expires.IsZero() {
+		expires := t.Expires
+		info.Expires = &expires
+	}
+	return info
+}
+
+func newAdminInfo(msg routing.AdminMessage) *adminInfo {
+	return &adminInfo{
+		Kind:    msg.Kind,
+		Target:  msg.Target,
+		Message: msg.Message,
+	}
+}
+
+func newOverInfo(over routing.GameOver) *overInfo {
+	info := &overInfo{
+		Winner:    over.Winner,
+		Reason:    over.Reason,
+		Standings: []standingInfo{},
+	}
+	for _, s := range over.Standings {
+		info.Standings = append(info.Standings, standingInfo{
+			Username:    s.Username,
+			Score:       s.Score,
+			Units:       s.Units,
+			Territories: s.Territories,
+		})
+	}
+	return info
+}
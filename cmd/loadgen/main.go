@@ -0,0 +1,219 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/client"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/config"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+var (
+	players   = flag.Int("players", 10, "number of simulated players, each with its own connection")
+	gameID    = flag.String("game", "loadgen", "game to play, created if it doesn't exist")
+	duration  = flag.Duration("duration", 30*time.Second, "how long to generate load for")
+	spawnRate = flag.Float64("spawn-rate", 0.2, "spawns per second per player")
+	moveRate  = flag.Float64("move-rate", 0.5, "moves per second per player")
+	logRate   = flag.Float64("log-rate", 1, "game logs per second per player")
+	hotspots  = flag.Int("hotspots", 2, "number of territories the moves go to, fewer means more wars")
+	drain     = flag.Duration("drain", 3*time.Second, "how long to wait for messages in flight at the end")
+	seed      = flag.Int64("seed", 0, "random seed, 0 picks one from the clock")
+)
+
+var configFlags = config.RegisterFlags(flag.CommandLine)
+
+func main() {
+	flag.Parse()
+	cfg, err := config.Load(configFlags)
+	if err != nil {
+		fmt.Printf("error loading config: %v\n", err)
+		return
+	}
+	fmt.Println("Starting Peril load generator...")
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(*seed))
+	locations := gamelogic.Locations()
+	rng.Shuffle(len(locations), func(i, j int) { locations[i], locations[j] = locations[j], locations[i] })
+	if *hotspots < 1 || *hotspots > len(locations) {
+		*hotspots = len(locations)
+	}
+	targets := locations[:*hotspots]
+	fmt.Printf("Using seed %d, moves go to %v\n", *seed, targets)
+
+	conn, err := cfg.Dial()
+	if err != nil {
+		fmt.Printf("unable to connect to AMQP server %s, %v\n", cfg.RedactedURL(), err)
+		return
+	}
+	defer conn.Close()
+
+	st := &stats{}
+	err = observe(conn, *gameID, st)
+	if err != nil {
+		fmt.Printf("error subscribing to the game: %v\n", err)
+		return
+	}
+
+	// the sessions print everything that happens in the game, which would
+	// drown out the results
+	out := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err == nil {
+		os.Stdout = devNull
+	}
+
+	sessions := []*client.Session{}
+	for i := 0; i < *players; i++ {
+		name := fmt.Sprintf("load-%d", i+1)
+		s, err := join(conn, cfg, name)
+		if err != nil {
+			fmt.Fprintf(out, "error joining %s: %v\n", name, err)
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	fmt.Fprintf(out, "%d players joined %s, generating load for %v\n", len(sessions), *gameID, *duration)
+
+	start := time.Now()
+	stop := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	for i, s := range sessions {
+		p := &player{
+			session: s,
+			stats:   st,
+			targets: targets,
+			rng:     rand.New(rand.NewSource(*seed + int64(i) + 1)),
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.run(stop)
+		}()
+	}
+	time.Sleep(*duration)
+	close(stop)
+	wg.Wait()
+	elapsed := time.Since(start)
+	time.Sleep(*drain)
+
+	for _, s := range sessions {
+		s.Close()
+	}
+	st.print(out, elapsed)
+}
+
+func join(conn *amqp.Connection, cfg config.Config, name string) (*client.Session, error) {
+	lobby, err := client.NewLobby(conn, name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := lobby.Enter(routing.LobbyJoin, *gameID, false)
+	if err != nil {
+		info, err = lobby.Enter(routing.LobbyCreate, *gameID, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return client.Join(cfg, name, info, client.Hooks{})
+}
+
+// observe measures how long game events and logs take to get through the
+// broker, using the time they were sent at.
+func observe(conn *amqp.Connection, game string, st *stats) error {
+	err := pubsub.SubscribeJSON(
+		conn,
+		routing.ExchangePerilTopic,
+		routing.Key("loadgen", routing.GameEventsPrefix, game),
+		routing.Key(routing.GameEventsPrefix, game, "*"),
+		pubsub.QueueTypeTransient,
+		func(ev gamelogic.GameEvent) pubsub.AckType {
+			st.events.add(time.Since(ev.Time))
+			return pubsub.Ack
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return pubsub.SubscribeGob(
+		conn,
+		routing.ExchangePerilTopic,
+		routing.Key("loadgen", routing.GameLogSlug, game),
+		routing.Key(routing.GameLogSlug, game, "*"),
+		pubsub.QueueTypeTransient,
+		func(gl routing.GameLog) pubsub.AckType {
+			st.gameLogs.add(time.Since(gl.CurrentTime))
+			return pubsub.Ack
+		},
+	)
+}
+
+// player sends spawns, moves and logs at the configured rates. Moves all go
+// to the same few territories so players run into each other.
+type player struct {
+	session *client.Session
+	stats   *stats
+	targets []gamelogic.Location
+	rng     *rand.Rand
+}
+
+func (p *player) run(stop <-chan struct{}) {
+	spawns := ticker(*spawnRate)
+	moves := ticker(*moveRate)
+	logs := ticker(*logRate)
+	for {
+		select {
+		case <-spawns:
+			loc := gamelogic.Locations()[p.rng.Intn(len(gamelogic.Locations()))]
+			p.timed(&p.stats.spawns, func() error {
+				return p.session.Order([]string{"spawn", string(loc), gamelogic.RankInfantry})
+			})
+		case <-moves:
+			units := p.session.State.GetPlayerSnap().Units
+			for id, unit := range units {
+				if unit.InTransit() {
+					continue
+				}
+				to := p.targets[p.rng.Intn(len(p.targets))]
+				p.timed(&p.stats.moves, func() error {
+					return p.session.Order([]string{"move", string(to), fmt.Sprint(id)})
+				})
+				break
+			}
+		case <-logs:
+			p.timed(&p.stats.logs, func() error {
+				return p.session.Log(gamelogic.GetMaliciousLog())
+			})
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (p *player) timed(l *latencies, f func() error) {
+	start := time.Now()
+	err := f()
+	if err != nil {
+		l.fail()
+		return
+	}
+	l.add(time.Since(start))
+}
+
+// ticker fires rate times a second, or never if rate isn't positive.
+func ticker(rate float64) <-chan time.Time {
+	if rate <= 0 {
+		return nil
+	}
+	return time.NewTicker(time.Duration(float64(time.Second) / rate)).C
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencies collects samples and reports their percentiles.
+type latencies struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	errors  int
+}
+
+func (l *latencies) add(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.samples = append(l.samples, d)
+}
+
+func (l *latencies) fail() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors++
+}
+
+func (l *latencies) print(w io.Writer, name string, elapsed time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := len(l.samples)
+	rate := float64(n) / elapsed.Seconds()
+	fmt.Fprintf(w, "%s: %d ok, %d failed, %.1f/s\n", name, n, l.errors, rate)
+	if n == 0 {
+		return
+	}
+	sorted := append([]time.Duration{}, l.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	fmt.Fprintf(w, "  p50 %v, p90 %v, p99 %v, max %v\n",
+		percentile(sorted, 50), percentile(sorted, 90), percentile(sorted, 99), sorted[n-1])
+}
+
+// percentile expects sorted samples.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// stats is everything measured during a run. Publish latency is how long an
+// order took to send, consume latency how long its message took to come
+// back through a subscription.
+type stats struct {
+	spawns   latencies
+	moves    latencies
+	logs     latencies
+	events   latencies
+	gameLogs latencies
+}
+
+func (s *stats) print(w io.Writer, elapsed time.Duration) {
+	fmt.Fprintf(w, "==== Load test results over %v ====\n", elapsed.Round(time.Millisecond))
+	fmt.Fprintln(w, "Published:")
+	s.spawns.print(w, "spawns", elapsed)
+	s.moves.print(w, "moves", elapsed)
+	s.logs.print(w, "logs", elapsed)
+	fmt.Fprintln(w, "Consumed:")
+	s.events.print(w, "game events", elapsed)
+	s.gameLogs.print(w, "game logs", elapsed)
+	fmt.Fprintln(w, "------------------------")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/config"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/inspect"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/recording"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+var (
+	out   = flag.String("out", "recording.jsonl", "file to write the recording to")
+	games = flag.String("games", routing.DefaultGame, "comma separated games to record on peril_direct")
+	users = flag.String("users", "", "comma separated users to record on peril_direct")
+)
+
+var configFlags = config.RegisterFlags(flag.CommandLine)
+
+func main() {
+	flag.Parse()
+	cfg, err := config.Load(configFlags)
+	if err != nil {
+		fmt.Printf("error loading config: %v\n", err)
+		return
+	}
+	conn, err := cfg.Dial()
+	if err != nil {
+		fmt.Printf("unable to connect to AMQP server %s, %v\n", cfg.RedactedURL(), err)
+		return
+	}
+	defer conn.Close()
+
+	f, err := os.Create(*out)
+	if err != nil {
+		fmt.Printf("error creating %s: %v\n", *out, err)
+		return
+	}
+	defer f.Close()
+
+	queueName := fmt.Sprintf("peril_record.%d", os.Getpid())
+	ch, q, err := pubsub.DeclareAndBind(conn, routing.ExchangePerilTopic, queueName, "#", pubsub.QueueTypeTransient)
+	if err != nil {
+		fmt.Printf("error binding to %s: %v\n", routing.ExchangePerilTopic, err)
+		return
+	}
+	// a direct exchange only delivers exact matches, so record the keys we
+	// know about
+	for _, key := range inspect.DirectKeys(split(*games), split(*users)) {
+		err := ch.QueueBind(q.Name, key, routing.ExchangePerilDirect, false, nil)
+		if err != nil {
+			fmt.Printf("error binding %s: %v\n", key, err)
+			return
+		}
+	}
+	deliveries, err := ch.Consume(q.Name, "", true, true, false, false, nil)
+	if err != nil {
+		fmt.Printf("error while calling Consume: %v\n", err)
+		return
+	}
+
+	rec := recording.NewRecorder(f)
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	fmt.Printf("Recording Peril traffic to %s, press Ctrl+C to stop...\n", *out)
+	for {
+		select {
+		case d, ok := <-deliveries:
+			if !ok {
+				fmt.Println("the connection to the broker was closed")
+				return
+			}
+			err := rec.Record(d)
+			if err != nil {
+				fmt.Printf("error recording delivery: %v\n", err)
+			}
+		case <-interrupt:
+			fmt.Printf("Recorded %d messages.\n", rec.Count())
+			return
+		}
+	}
+}
+
+func split(list string) []string {
+	parts := []string{}
+	for _, p := range strings.Split(list, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
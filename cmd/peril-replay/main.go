@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/config"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/inspect"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/recording"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+var (
+	in     = flag.String("in", "recording.jsonl", "recording to replay")
+	speed  = flag.Float64("speed", 1, "playback speed, 2 is twice as fast and 0 is as fast as possible")
+	inproc = flag.Bool("inproc", false, "replay into an in-process broker and print what it delivers, instead of a real broker")
+	bind   = flag.String("bind", routing.ExchangePerilTopic+":#", "comma separated exchange:key bindings to print with -inproc")
+)
+
+var configFlags = config.RegisterFlags(flag.CommandLine)
+
+func main() {
+	flag.Parse()
+	cfg, err := config.Load(configFlags)
+	if err != nil {
+		fmt.Printf("error loading config: %v\n", err)
+		return
+	}
+
+	f, err := os.Open(*in)
+	if err != nil {
+		fmt.Printf("error opening %s: %v\n", *in, err)
+		return
+	}
+	entries, err := recording.Load(f)
+	f.Close()
+	if err != nil {
+		fmt.Printf("error loading %s: %v\n", *in, err)
+		return
+	}
+	fmt.Printf("Replaying %d messages from %s at speed %v\n", len(entries), *in, *speed)
+
+	stop := make(chan struct{})
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		close(stop)
+	}()
+
+	if *inproc {
+		err = replayInProcess(entries, stop)
+	} else {
+		err = replayToBroker(cfg, entries, stop)
+	}
+	if err != nil {
+		fmt.Printf("error replaying: %v\n", err)
+		return
+	}
+	fmt.Println("Replay finished.")
+}
+
+func replayToBroker(cfg config.Config, entries []recording.Entry, stop <-chan struct{}) error {
+	conn, err := cfg.Dial()
+	if err != nil {
+		return fmt.Errorf("unable to connect to AMQP server %s: %w", cfg.RedactedURL(), err)
+	}
+	defer conn.Close()
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("error creating channel: %w", err)
+	}
+	return recording.Replay(entries, ch, *speed, stop)
+}
+
+// replayInProcess routes the recording through an in-process broker, with
+// every binding on one queue so deliveries print in a fixed order.
+func replayInProcess(entries []recording.Entry, stop <-chan struct{}) error {
+	broker := recording.NewBroker()
+	var deliveries <-chan amqp.Delivery
+	for _, b := range strings.Split(*bind, ",") {
+		exchange, key, ok := strings.Cut(strings.TrimSpace(b), ":")
+		if !ok {
+			return fmt.Errorf("binding %q should look like exchange:key", b)
+		}
+		q, err := broker.Bind("replay", exchange, key)
+		if err != nil {
+			return err
+		}
+		deliveries = q
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for d := range deliveries {
+			printDelivery(d)
+		}
+	}()
+	err := recording.Replay(entries, broker, *speed, stop)
+	broker.Close()
+	<-done
+	return err
+}
+
+func printDelivery(d amqp.Delivery) {
+	fmt.Printf("%s %s", d.Exchange, d.RoutingKey)
+	msg, err := inspect.Decode(d.Exchange, d.RoutingKey, d.ContentType, d.Body)
+	if err != nil {
+		fmt.Printf(" (%s): %v\n", d.ContentType, err)
+		return
+	}
+	fmt.Printf(" %s\n", msg.Type)
+	body, err := json.MarshalIndent(msg.Value, "  ", "  ")
+	if err != nil {
+		fmt.Printf("  %+v\n", msg.Value)
+		return
+	}
+	fmt.Printf("  %s\n", body)
+}
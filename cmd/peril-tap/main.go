@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/config"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/inspect"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+var (
+	keyPattern = flag.String("key", "#", "only show messages whose routing key matches this topic pattern")
+	userFilter = flag.String("user", "", "only show messages to, from or about this user")
+	typeFilter = flag.String("type", "", "only show messages of this type, e.g. ArmyMove or routing.GameLog")
+	jsonLines  = flag.Bool("json", false, "print one JSON object per message instead of pretty printing")
+	games      = flag.String("games", routing.DefaultGame, "comma separated games to tap on peril_direct")
+	users      = flag.String("users", "", "comma separated users to tap on peril_direct")
+)
+
+// record is a message as printed with -json.
+type record struct {
+	Time        time.Time `json:"time"`
+	Exchange    string    `json:"exchange"`
+	Key         string    `json:"key"`
+	ContentType string    `json:"content_type"`
+	Type        string    `json:"type"`
+	Body        any       `json:"body"`
+	Error       string    `json:"error,omitempty"`
+}
+
+var configFlags = config.RegisterFlags(flag.CommandLine)
+
+func main() {
+	flag.Parse()
+	cfg, err := config.Load(configFlags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading config: %v\n", err)
+		return
+	}
+	conn, err := cfg.Dial()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to connect to AMQP server %s, %v\n", cfg.RedactedURL(), err)
+		return
+	}
+	defer conn.Close()
+
+	deliveries, err := tap(conn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error tapping the exchanges: %v\n", err)
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Tapping Peril traffic, press Ctrl+C to stop...")
+
+	enc := json.NewEncoder(os.Stdout)
+	for d := range deliveries {
+		if !routing.MatchTopic(*keyPattern, d.RoutingKey) {
+			continue
+		}
+		rec := record{
+			Time:        time.Now(),
+			Exchange:    d.Exchange,
+			Key:         d.RoutingKey,
+			ContentType: d.ContentType,
+		}
+		msg, err := inspect.Decode(d.Exchange, d.RoutingKey, d.ContentType, d.Body)
+		if err != nil {
+			rec.Error = err.Error()
+			rec.Body = string(d.Body)
+		} else {
+			rec.Type = msg.Type
+			rec.Body = msg.Value
+		}
+		if !wanted(rec, msg) {
+			continue
+		}
+		if *jsonLines {
+			err := enc.Encode(rec)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error encoding message: %v\n", err)
+			}
+			continue
+		}
+		printRecord(rec)
+	}
+}
+
+// tap binds a transient queue to everything on peril_topic, and to the keys
+// we can guess on peril_direct. A direct exchange only delivers exact key
+// matches, so direct traffic is limited to the games and users given.
+func tap(conn *amqp.Connection) (<-chan amqp.Delivery, error) {
+	queueName := fmt.Sprintf("peril_tap.%d", os.Getpid())
+	ch, q, err := pubsub.DeclareAndBind(conn, routing.ExchangePerilTopic, queueName, "#", pubsub.QueueTypeTransient)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range inspect.DirectKeys(split(*games), split(*users)) {
+		err := ch.QueueBind(q.Name, key, routing.ExchangePerilDirect, false, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error binding %s: %w", key, err)
+		}
+	}
+	return ch.Consume(q.Name, "", true, true, false, false, nil)
+}
+
+func split(list string) []string {
+	parts := []string{}
+	for _, p := range strings.Split(list, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+func wanted(rec record, msg inspect.Message) bool {
+	if *typeFilter != "" {
+		t := strings.ToLower(rec.Type)
+		want := strings.ToLower(*typeFilter)
+		if t != want && !strings.HasSuffix(t, "."+want) {
+			return false
+		}
+	}
+	if *userFilter != "" && !slices.Contains(inspect.Users(rec.Key, msg), *userFilter) {
+		return false
+	}
+	return true
+}
+
+func printRecord(rec record) {
+	fmt.Printf("%s %s %s", rec.Time.Format("15:04:05.000"), rec.Exchange, rec.Key)
+	if rec.Error != "" {
+		fmt.Printf(" (%s): %s\n", rec.ContentType, rec.Error)
+		return
+	}
+	fmt.Printf(" %s\n", rec.Type)
+	body, err := json.MarshalIndent(rec.Body, "  ", "  ")
+	if err != nil {
+		fmt.Printf("  %+v\n", rec.Body)
+		return
+	}
+	fmt.Printf("  %s\n", body)
+}
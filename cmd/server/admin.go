@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+func commandKick(ch *amqp.Channel, g *game, words []string) error {
+	if len(words) != 2 {
+		return fmt.Errorf("usage: kick <user>")
+	}
+	username := words[1]
+	err := g.presence.kick(username)
+	if err != nil {
+		return err
+	}
+	return pubAdmin(ch, routing.AdminMessage{
+		Game:   g.id,
+		Kind:   routing.AdminKick,
+		Target: username,
+	})
+}
+
+func commandReset(ch *amqp.Channel, g *game) error {
+	err := recordEvent(ch, g.world, gamelogic.NewResetEvent(g.id))
+	if err != nil {
+		return err
+	}
+	g.ref.reset()
+	g.diplomat.diplomacy.Reset()
+	err = pubAdmin(ch, routing.AdminMessage{
+		Game: g.id,
+		Kind: routing.AdminReset,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Game %s has been reset.\n", g.id)
+	return nil
+}
+
+func commandBroadcast(ch *amqp.Channel, g *game, words []string) error {
+	if len(words) < 2 {
+		return fmt.Errorf("usage: broadcast <message>")
+	}
+	return pubAdmin(ch, routing.AdminMessage{
+		Game:    g.id,
+		Kind:    routing.AdminBroadcast,
+		Message: strings.Join(words[1:], " "),
+	})
+}
+
+func pubAdmin(ch *amqp.Channel, msg routing.AdminMessage) error {
+	msg.Time = time.Now()
+	key := routing.Key(routing.AdminPrefix, msg.Game)
+	err := pubsub.PublishJSON(ch, routing.ExchangePerilDirect, key, msg)
+	if err != nil {
+		return fmt.Errorf("error publishing admin message: %w", err)
+	}
+	return nil
+}
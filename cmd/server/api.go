@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+var (
+	apiAddr  = flag.String("http", "", "serve the admin API on this address, e.g. :8080")
+	apiToken = flag.String("http-token", "", "bearer token for the admin API (default $PERIL_ADMIN_TOKEN)")
+)
+
+// adminAPI lets scripts run the server over HTTP. Every request needs
+// "Authorization: Bearer <token>". The games are picked with ?game=, which
+// defaults to the default game.
+//
+//	POST /pause                    pause a game
+//	POST /resume                   resume a game
+//	GET  /players                  players online, in one game or all
+//	GET  /logs?user=&game=&since=  game logs, since is RFC 3339 or a duration
+//	GET  /health                   whether the server is connected
+//	GET  /metrics                  Prometheus metrics
+type adminAPI struct {
+	conn    *amqp.Connection
+	ch      *amqp.Channel
+	games   *lobby
+	dlq     *deadLetters
+	token   string
+	started time.Time
+}
+
+type apiPlayer struct {
+	Game     string    `json:"game"`
+	Username string    `json:"username"`
+	LastSeen time.Time `json:"last_seen"`
+	Units    int       `json:"units"`
+}
+
+type apiLog struct {
+	Time     time.Time `json:"time"`
+	Game     string    `json:"game"`
+	Username string    `json:"username"`
+	Message  string    `json:"message"`
+}
+
+// serveAdminAPI starts the admin API in the background if -http is set. The
+// server it returns is nil otherwise.
+func serveAdminAPI(conn *amqp.Connection, ch *amqp.Channel, games *lobby, dlq *deadLetters) (*http.Server, error) {
+	if *apiAddr == "" {
+		return nil, nil
+	}
+	token := *apiToken
+	if token == "" {
+		token = os.Getenv("PERIL_ADMIN_TOKEN")
+	}
+	if token == "" {
+		return nil, errors.New("the admin API needs a token, set -http-token or PERIL_ADMIN_TOKEN")
+	}
+
+	api := &adminAPI{
+		conn:    conn,
+		ch:      ch,
+		games:   games,
+		dlq:     dlq,
+		token:   token,
+		started: time.Now(),
+	}
+	srv := &http.Server{
+		Addr:              *apiAddr,
+		Handler:           api.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		err := srv.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("error serving admin API: %v\n", err)
+		}
+	}()
+	fmt.Printf("Admin API listening on %s\n", *apiAddr)
+	return srv, nil
+}
+
+func stopAdminAPI(srv *http.Server) {
+	if srv == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := srv.Shutdown(ctx)
+	if err != nil {
+		fmt.Printf("error stopping admin API: %v\n", err)
+	}
+}
+
+func (api *adminAPI) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /pause", api.handlePause(true))
+	mux.HandleFunc("POST /resume", api.handlePause(false))
+	mux.HandleFunc("GET /players", api.handlePlayers)
+	mux.HandleFunc("GET /logs", api.handleLogs)
+	mux.HandleFunc("GET /health", api.handleHealth)
+	mux.HandleFunc("GET /metrics", api.handleMetrics)
+	return api.authorize(mux)
+}
+
+func (api *adminAPI) authorize(next http.Handler) http.Handler {
+	want := []byte("Bearer " + api.token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("missing or wrong bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (api *adminAPI) game(r *http.Request) (*game, error) {
+	id := r.URL.Query().Get("game")
+	if id == "" {
+		id = routing.DefaultGame
+	}
+	g, ok := api.games.get(id)
+	if !ok {
+		return nil, fmt.Errorf("game %s does not exist", id)
+	}
+	return g, nil
+}
+
+func (api *adminAPI) handlePause(paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, err := api.game(r)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		err = setPaused(api.ch, g, paused)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if paused {
+			fmt.Printf("Game %s paused through the admin API\n", g.id)
+		} else {
+			fmt.Printf("Game %s resumed through the admin API\n", g.id)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"game": g.id, "paused": paused})
+	}
+}
+
+func (api *adminAPI) handlePlayers(w http.ResponseWriter, r *http.Request) {
+	games := []*game{}
+	if r.URL.Query().Get("game") != "" {
+		g, err := api.game(r)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		games = append(games, g)
+	} else {
+		for _, info := range api.games.list() {
+			if g, ok := api.games.get(info.ID); ok {
+				games = append(games, g)
+			}
+		}
+	}
+
+	players := []apiPlayer{}
+	for _, g := range games {
+		for _, pi := range g.presence.online() {
+			players = append(players, apiPlayer{
+				Game:     g.id,
+				Username: pi.Username,
+				LastSeen: pi.LastSeen,
+				Units:    pi.Units,
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, players)
+}
+
+func (api *adminAPI) handleLogs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	since, err := parseSince(q.Get("since"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	logs, err := gamelogic.ReadLogs(q.Get("game"), q.Get("user"), since)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	entries := []apiLog{}
+	for _, gl := range logs {
+		entries = append(entries, apiLog{
+			Time:     gl.CurrentTime,
+			Game:     gl.Game,
+			Username: gl.Username,
+			Message:  gl.Message,
+		})
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// parseSince takes a time or how long ago, e.g. 2024-05-01T12:00:00Z or 10m.
+func parseSince(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("since must be an RFC 3339 time or a duration: %s", s)
+	}
+	return time.Now().Add(-d), nil
+}
+
+func (api *adminAPI) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if api.conn.IsClosed() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "broker connection closed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "ok",
+		"games":  len(api.games.list()),
+		"uptime": time.Since(api.started).Round(time.Second).String(),
+	})
+}
+
+func (api *adminAPI) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	writeMetrics(w, api)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		fmt.Printf("error writing admin API response: %v\n", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": strings.TrimSpace(err.Error())})
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+)
+
+func TestAdminAPIAuth(t *testing.T) {
+	oldFile := gamelogic.LogsFile
+	t.Cleanup(func() { gamelogic.LogsFile = oldFile })
+	gamelogic.LogsFile = filepath.Join(t.TempDir(), "game.log")
+
+	api := &adminAPI{token: "secret"}
+	srv := httptest.NewServer(api.handler())
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"missing token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer guess", http.StatusUnauthorized},
+		{"token without bearer", "secret", http.StatusUnauthorized},
+		{"longer token", "Bearer secrets", http.StatusUnauthorized},
+		{"right token", "Bearer secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", srv.URL+"/logs", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if tt.want != http.StatusUnauthorized {
+				return
+			}
+			if resp.Header.Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q, want Bearer", resp.Header.Get("WWW-Authenticate"))
+			}
+			var body map[string]string
+			err = json.NewDecoder(resp.Body).Decode(&body)
+			if err != nil || body["error"] == "" {
+				t.Errorf("body = %v, %v, want a JSON error", body, err)
+			}
+		})
+	}
+}
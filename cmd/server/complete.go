@@ -0,0 +1,50 @@
+package main
+
+import (
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/console"
+)
+
+var serverCommands = []string{
+	"games", "create", "use", "pause", "resume", "replay", "players", "kick",
+	"reset", "broadcast", "treaties", "standings", "turns", "dlq", "quit", "help",
+}
+
+// completeCommand completes the server commands, their subcommands and the
+// names of games.
+func completeCommand(games *lobby) console.Completer {
+	return func(words []string) []string {
+		if len(words) == 1 {
+			return serverCommands
+		}
+		arg := len(words) - 1
+		switch words[0] {
+		case "use", "replay":
+			if arg == 1 {
+				ids := []string{}
+				for _, info := range games.list() {
+					ids = append(ids, info.ID)
+				}
+				return ids
+			}
+			if words[0] == "replay" && arg == 2 {
+				return []string{"until"}
+			}
+		case "create":
+			if arg == 2 {
+				return []string{"fog"}
+			}
+		case "turns":
+			if arg == 1 {
+				return []string{"start", "stop"}
+			}
+		case "dlq":
+			if arg == 1 {
+				return []string{"list", "show", "requeue", "purge"}
+			}
+			if words[1] == "requeue" && arg == 2 {
+				return []string{"all"}
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+// diplomat arbitrates treaties for a game. Every change is sent to both
+// parties along with their full list of treaties, so the two sides can't
+// disagree, and accepted, broken and expired treaties are announced to
+// everyone. Treaties are recorded with the game's events and restored from
+// them.
+type diplomat struct {
+	ch        pubsub.Publisher
+	game      string
+	world     *gamelogic.World
+	diplomacy *gamelogic.Diplomacy
+	// inGame reports whether a player is in the game, so treaties are only
+	// proposed to players who can accept them.
+	inGame func(username string) bool
+}
+
+func newDiplomat(ch pubsub.Publisher, world *gamelogic.World) *diplomat {
+	d := &diplomat{
+		ch:        ch,
+		game:      world.Game,
+		world:     world,
+		diplomacy: gamelogic.NewDiplomacy(),
+	}
+	d.diplomacy.Restore(world.Treaties())
+	return d
+}
+
+func (d *diplomat) run() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		d.expire(time.Now())
+	}
+}
+
+// expire ends the ceasefires that have run out by now.
+func (d *diplomat) expire(now time.Time) {
+	for _, t := range d.diplomacy.Expire(now) {
+		err := d.record(t, false)
+		if err != nil {
+			fmt.Printf("error recording expired treaty: %v\n", err)
+		}
+		err = d.announce(routing.DiplomacyExpired, t, "")
+		if err != nil {
+			fmt.Printf("error announcing expired treaty: %v\n", err)
+		}
+	}
+}
+
+func (d *diplomat) handle(req routing.DiplomacyRequest) error {
+	switch req.Action {
+	case routing.DiplomacyPropose:
+		if !d.inGame(req.Target) {
+			return d.reject(req.From, fmt.Errorf("%s is not in game %s", req.Target, d.game))
+		}
+		t, err := d.diplomacy.Propose(req.From, req.Target, req.Kind, req.Duration)
+		if err != nil {
+			return d.reject(req.From, err)
+		}
+		err = d.send(req.Target, routing.DiplomacyProposed, t, "")
+		if err != nil {
+			return err
+		}
+		return d.send(req.From, routing.DiplomacyProposed, t, "")
+	case routing.DiplomacyAccept:
+		t, err := d.diplomacy.Accept(req.From, req.Target)
+		if err != nil {
+			return d.reject(req.From, err)
+		}
+		err = d.record(t, true)
+		if err != nil {
+			return err
+		}
+		return d.announce(routing.DiplomacyAccepted, t, "")
+	case routing.DiplomacyBreak:
+		t, err := d.diplomacy.Break(req.From, req.Target, req.Kind)
+		if err != nil {
+			return d.reject(req.From, err)
+		}
+		err = d.record(t, false)
+		if err != nil {
+			return err
+		}
+		reason := fmt.Sprintf("%s broke the %s with %s", req.From, t.Kind, req.Target)
+		err = gamelogic.WriteLog(routing.GameLog{
+			CurrentTime: time.Now(),
+			Message:     reason,
+			Username:    req.From,
+			Game:        d.game,
+		})
+		if err != nil {
+			fmt.Printf("error logging broken treaty: %v\n", err)
+		}
+		return d.announce(routing.DiplomacyBroken, t, reason)
+	default:
+		return d.reject(req.From, fmt.Errorf("unknown diplomacy action: %s", req.Action))
+	}
+}
+
+// forget drops the treaties of a player who left the game.
+func (d *diplomat) forget(username string) {
+	for _, t := range d.diplomacy.Forget(username) {
+		err := d.record(t, false)
+		if err != nil {
+			fmt.Printf("error recording dropped treaty: %v\n", err)
+		}
+		reason := fmt.Sprintf("%s left the game", username)
+		err = d.announce(routing.DiplomacyBroken, t, reason)
+		if err != nil {
+			fmt.Printf("error announcing dropped treaty: %v\n", err)
+		}
+	}
+}
+
+// record stores a treaty coming into force or ending with the game's events,
+// so a restart doesn't dissolve it.
+func (d *diplomat) record(t routing.Treaty, signed bool) error {
+	return recordEvent(d.ch, d.world, gamelogic.NewTreatyEvent(d.game, t, signed))
+}
+
+// announce tells both parties about a change to their treaty, and then
+// everyone else in the game.
+func (d *diplomat) announce(event string, t routing.Treaty, reason string) error {
+	err := d.send(t.Proposer, event, t, reason)
+	if err != nil {
+		return err
+	}
+	err = d.send(t.Partner, event, t, reason)
+	if err != nil {
+		return err
+	}
+	du := routing.DiplomacyUpdate{
+		Game:   d.game,
+		Event:  event,
+		Treaty: t,
+		Reason: reason,
+	}
+	key := routing.Key(routing.DiplomacyPrefix, d.game)
+	err = pubsub.PublishJSON(d.ch, routing.ExchangePerilDirect, key, du)
+	if err != nil {
+		return fmt.Errorf("error publishing diplomacy announcement: %w", err)
+	}
+	fmt.Printf("[%s] %s: %s %s\n", d.game, event, t.Kind, reason)
+	return nil
+}
+
+func (d *diplomat) send(username, event string, t routing.Treaty, reason string) error {
+	du := routing.DiplomacyUpdate{
+		Game:     d.game,
+		Event:    event,
+		Treaty:   t,
+		Reason:   reason,
+		Treaties: d.diplomacy.TreatiesOf(username),
+	}
+	key := routing.Key(routing.DiplomacyPrefix, d.game, username)
+	err := pubsub.PublishJSON(d.ch, routing.ExchangePerilDirect, key, du)
+	if err != nil {
+		return fmt.Errorf("error publishing diplomacy update to %s: %w", username, err)
+	}
+	return nil
+}
+
+func (d *diplomat) reject(username string, reason error) error {
+	du := routing.DiplomacyUpdate{
+		Game:     d.game,
+		Event:    routing.DiplomacyRejected,
+		Reason:   reason.Error(),
+		Treaties: d.diplomacy.TreatiesOf(username),
+	}
+	key := routing.Key(routing.DiplomacyPrefix, d.game, username)
+	err := pubsub.PublishJSON(d.ch, routing.ExchangePerilDirect, key, du)
+	if err != nil {
+		return fmt.Errorf("error publishing diplomacy rejection to %s: %w", username, err)
+	}
+	return nil
+}
+
+func commandTreaties(d *diplomat) {
+	treaties := d.diplomacy.All()
+	if len(treaties) == 0 {
+		fmt.Printf("There are no treaties in %s.\n", d.game)
+		return
+	}
+	for _, t := range treaties {
+		if t.Expires.IsZero() {
+			fmt.Printf("* %s between %s and %s\n", t.Kind, t.Proposer, t.Partner)
+			continue
+		}
+		fmt.Printf("* %s between %s and %s, %v left\n", t.Kind, t.Proposer, t.Partner, time.Until(t.Expires).Round(time.Second))
+	}
+}
+
+func handlerDiplomacy(l *lobby) func(routing.DiplomacyRequest) pubsub.AckType {
+	f := func(req routing.DiplomacyRequest) pubsub.AckType {
+		handled.inc(routing.DiplomacyPrefix)
+		defer fmt.Print("> ")
+		g, ok := l.get(req.Game)
+		if !ok {
+			fmt.Printf("ignoring diplomacy from %s, game %s does not exist\n", req.From, req.Game)
+			return pubsub.NackDiscard
+		}
+		err := g.diplomat.handle(req)
+		if err != nil {
+			fmt.Printf("error handling diplomacy from %s: %v\n", req.From, err)
+			return pubsub.NackRequeue
+		}
+		return pubsub.Ack
+	}
+	return f
+}
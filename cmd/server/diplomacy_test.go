@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/recording"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+// newTestDiplomat runs a diplomat for alice and bob, restored from the
+// events stored so far, and returns it with alice's diplomacy updates.
+func newTestDiplomat(t *testing.T, broker *recording.Broker) (*diplomat, <-chan amqp.Delivery) {
+	t.Helper()
+	updates, err := broker.Bind("alice", routing.ExchangePerilDirect, routing.Key(routing.DiplomacyPrefix, "test", "alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	world, err := loadWorld("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newDiplomat(broker, world)
+	d.inGame = func(username string) bool { return username == "alice" || username == "bob" }
+	return d, updates
+}
+
+// lastUpdate is the last diplomacy update waiting in updates.
+func lastUpdate(t *testing.T, updates <-chan amqp.Delivery) routing.DiplomacyUpdate {
+	t.Helper()
+	var du routing.DiplomacyUpdate
+	for {
+		select {
+		case d := <-updates:
+			err := json.Unmarshal(d.Body, &du)
+			if err != nil {
+				t.Fatal(err)
+			}
+		default:
+			return du
+		}
+	}
+}
+
+func TestDiplomatPropose(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		kind   string
+		want   string
+	}{
+		{name: "to a player", target: "bob", kind: routing.TreatyAlliance, want: routing.DiplomacyProposed},
+		{name: "to someone not in the game", target: "carol", kind: routing.TreatyAlliance, want: routing.DiplomacyRejected},
+		{name: "to yourself", target: "alice", kind: routing.TreatyAlliance, want: routing.DiplomacyRejected},
+		{name: "not a treaty", target: "bob", kind: "marriage", want: routing.DiplomacyRejected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inTempDir(t)
+			broker := recording.NewBroker()
+			defer broker.Close()
+			d, updates := newTestDiplomat(t, broker)
+			err := d.handle(routing.DiplomacyRequest{Game: "test", From: "alice", Action: routing.DiplomacyPropose, Kind: tt.kind, Target: tt.target})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if du := lastUpdate(t, updates); du.Event != tt.want {
+				t.Errorf("alice got %q (%s), want %q", du.Event, du.Reason, tt.want)
+			}
+		})
+	}
+}
+
+// TestDiplomatTreatiesRecorded checks accepted treaties outlive a restart
+// and broken and expired ones stay gone.
+func TestDiplomatTreatiesRecorded(t *testing.T) {
+	tests := []struct {
+		name  string
+		kind  string
+		end   func(d *diplomat) error
+		peace bool
+	}{
+		{
+			name:  "accepted",
+			kind:  routing.TreatyAlliance,
+			end:   func(d *diplomat) error { return nil },
+			peace: true,
+		},
+		{
+			name: "broken",
+			kind: routing.TreatyAlliance,
+			end: func(d *diplomat) error {
+				return d.handle(routing.DiplomacyRequest{Game: "test", From: "bob", Action: routing.DiplomacyBreak, Kind: routing.TreatyAlliance, Target: "alice"})
+			},
+		},
+		{
+			name: "expired",
+			kind: routing.TreatyCeasefire,
+			end: func(d *diplomat) error {
+				d.expire(time.Now().Add(time.Hour))
+				return nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inTempDir(t)
+			broker := recording.NewBroker()
+			defer broker.Close()
+			d, updates := newTestDiplomat(t, broker)
+			requests := []routing.DiplomacyRequest{
+				{Game: "test", From: "alice", Action: routing.DiplomacyPropose, Kind: tt.kind, Target: "bob", Duration: time.Minute},
+				{Game: "test", From: "bob", Action: routing.DiplomacyAccept, Target: "alice"},
+			}
+			for _, req := range requests {
+				err := d.handle(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if du := lastUpdate(t, updates); du.Event != routing.DiplomacyAccepted || len(du.Treaties) != 1 {
+				t.Fatalf("alice got %+v, want the accepted treaty", du)
+			}
+			err := tt.end(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			restarted, _ := newTestDiplomat(t, broker)
+			if got := restarted.diplomacy.AtPeace("alice", "bob", time.Now()); got != tt.peace {
+				t.Errorf("at peace after a restart = %v, want %v", got, tt.peace)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/inspect"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+// maxDeadLetters caps how many dead letters are looked at in one go.
+const maxDeadLetters = 1000
+
+// deadLetters manages the queue where rejected messages end up.
+type deadLetters struct {
+	ch *amqp.Channel
+}
+
+// newDeadLetters declares the dead letter exchange and queue that every
+// queue made by pubsub.DeclareAndBind points at.
+func newDeadLetters(conn *amqp.Connection) (*deadLetters, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("error creating channel: %w", err)
+	}
+	err = ch.ExchangeDeclare(routing.ExchangePerilDLX, amqp.ExchangeFanout, true, false, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error declaring dead letter exchange: %w", err)
+	}
+	_, err = ch.QueueDeclare(routing.DeadLetterQueue, true, false, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error declaring dead letter queue: %w", err)
+	}
+	err = ch.QueueBind(routing.DeadLetterQueue, "", routing.ExchangePerilDLX, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error binding dead letter queue: %w", err)
+	}
+	return &deadLetters{ch: ch}, nil
+}
+
+// count is how many dead letters are waiting.
+func (dl *deadLetters) count() (int, error) {
+	q, err := dl.ch.QueueDeclarePassive(routing.DeadLetterQueue, true, false, false, false, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error inspecting dead letter queue: %w", err)
+	}
+	return q.Messages, nil
+}
+
+// fetch takes the dead letters off the queue without acking them. They go
+// back with release, or are acked once dealt with.
+func (dl *deadLetters) fetch() ([]amqp.Delivery, error) {
+	msgs := []amqp.Delivery{}
+	for len(msgs) < maxDeadLetters {
+		d, ok, err := dl.ch.Get(routing.DeadLetterQueue, false)
+		if err != nil {
+			dl.release(msgs)
+			return nil, fmt.Errorf("error getting dead letters: %w", err)
+		}
+		if !ok {
+			break
+		}
+		msgs = append(msgs, d)
+	}
+	return msgs, nil
+}
+
+func (dl *deadLetters) release(msgs []amqp.Delivery) {
+	for _, d := range msgs {
+		err := d.Nack(false, true)
+		if err != nil {
+			fmt.Printf("error returning dead letter: %v\n", err)
+		}
+	}
+}
+
+// death is the most recent entry of a message's x-death header.
+type death struct {
+	reason   string
+	queue    string
+	exchange string
+	keys     []string
+	count    int64
+}
+
+func lastDeath(d amqp.Delivery) (death, bool) {
+	deaths, ok := d.Headers["x-death"].([]interface{})
+	if !ok || len(deaths) == 0 {
+		return death{}, false
+	}
+	t, ok := deaths[0].(amqp.Table)
+	if !ok {
+		return death{}, false
+	}
+	dt := death{}
+	dt.reason, _ = t["reason"].(string)
+	dt.queue, _ = t["queue"].(string)
+	dt.exchange, _ = t["exchange"].(string)
+	dt.count, _ = t["count"].(int64)
+	keys, _ := t["routing-keys"].([]interface{})
+	for _, k := range keys {
+		if s, ok := k.(string); ok {
+			dt.keys = append(dt.keys, s)
+		}
+	}
+	return dt, true
+}
+
+// origin is where a dead letter was first published.
+func origin(d amqp.Delivery) (exchange, key string) {
+	if dt, ok := lastDeath(d); ok && len(dt.keys) > 0 {
+		return dt.exchange, dt.keys[0]
+	}
+	return d.Exchange, d.RoutingKey
+}
+
+func printDeadLetter(n int, d amqp.Delivery) {
+	exchange, key := origin(d)
+	fmt.Printf("%d: %s %s (%s)\n", n, exchange, key, d.ContentType)
+	if dt, ok := lastDeath(d); ok {
+		fmt.Printf("   %s in queue %s, %d time(s)\n", dt.reason, dt.queue, dt.count)
+	}
+}
+
+func commandDLQ(dl *deadLetters, words []string) error {
+	if len(words) < 2 {
+		return errors.New("usage: dlq list | show <n> | requeue <n|all> | purge")
+	}
+	switch words[1] {
+	case "list":
+		msgs, err := dl.fetch()
+		if err != nil {
+			return err
+		}
+		defer dl.release(msgs)
+		if len(msgs) == 0 {
+			fmt.Println("The dead letter queue is empty.")
+			return nil
+		}
+		for i, d := range msgs {
+			printDeadLetter(i+1, d)
+		}
+		return nil
+	case "show":
+		if len(words) != 3 {
+			return errors.New("usage: dlq show <n>")
+		}
+		msgs, err := dl.fetch()
+		if err != nil {
+			return err
+		}
+		defer dl.release(msgs)
+		n, err := pickDeadLetter(words[2], len(msgs))
+		if err != nil {
+			return err
+		}
+		d := msgs[n-1]
+		printDeadLetter(n, d)
+		exchange, key := origin(d)
+		msg, err := inspect.Decode(exchange, key, d.ContentType, d.Body)
+		if err != nil {
+			fmt.Printf("   can't decode: %v\n", err)
+			fmt.Printf("   %q\n", d.Body)
+			return nil
+		}
+		body, err := json.MarshalIndent(msg.Value, "   ", "  ")
+		if err != nil {
+			return fmt.Errorf("error formatting %s: %w", msg.Type, err)
+		}
+		fmt.Printf("   %s\n   %s\n", msg.Type, body)
+		return nil
+	case "requeue":
+		if len(words) != 3 {
+			return errors.New("usage: dlq requeue <n|all>")
+		}
+		msgs, err := dl.fetch()
+		if err != nil {
+			return err
+		}
+		picked := msgs
+		rest := []amqp.Delivery{}
+		if words[2] != "all" {
+			n, err := pickDeadLetter(words[2], len(msgs))
+			if err != nil {
+				dl.release(msgs)
+				return err
+			}
+			picked = msgs[n-1 : n]
+			rest = append(append(rest, msgs[:n-1]...), msgs[n:]...)
+		}
+		defer dl.release(rest)
+		for i, d := range picked {
+			err := dl.requeue(d)
+			if err != nil {
+				dl.release(picked[i:])
+				return err
+			}
+		}
+		fmt.Printf("Requeued %d message(s).\n", len(picked))
+		return nil
+	case "purge":
+		n, err := dl.ch.QueuePurge(routing.DeadLetterQueue, false)
+		if err != nil {
+			return fmt.Errorf("error purging dead letters: %w", err)
+		}
+		fmt.Printf("Purged %d message(s).\n", n)
+		return nil
+	default:
+		return fmt.Errorf("unknown dlq command: %s", words[1])
+	}
+}
+
+// requeue publishes d to where it was first sent, and takes it off the dead
+// letter queue.
+func (dl *deadLetters) requeue(d amqp.Delivery) error {
+	exchange, key := origin(d)
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		if !strings.HasPrefix(k, "x-") {
+			headers[k] = v
+		}
+	}
+	err := dl.ch.PublishWithContext(context.Background(), exchange, key, false, false, amqp.Publishing{
+		ContentType: d.ContentType,
+		Headers:     headers,
+		Body:        d.Body,
+	})
+	if err != nil {
+		return fmt.Errorf("error republishing to %s %s: %w", exchange, key, err)
+	}
+	err = d.Ack(false)
+	if err != nil {
+		return fmt.Errorf("error removing dead letter: %w", err)
+	}
+	return nil
+}
+
+func pickDeadLetter(word string, count int) (int, error) {
+	n, err := strconv.Atoi(word)
+	if err != nil || n < 1 || n > count {
+		return 0, fmt.Errorf("%s is not a dead letter, there are %d", word, count)
+	}
+	return n, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// deadLetter is a message rejected from queue after being published to key.
+func deadLetter(queue, key string) amqp.Delivery {
+	return amqp.Delivery{
+		Exchange:   "peril_dlx",
+		RoutingKey: key,
+		Headers: amqp.Table{
+			"x-death": []interface{}{
+				amqp.Table{
+					"reason":       "rejected",
+					"queue":        queue,
+					"exchange":     "peril_topic",
+					"routing-keys": []interface{}{key},
+					"count":        int64(2),
+				},
+				amqp.Table{
+					"reason":   "expired",
+					"queue":    "older",
+					"exchange": "peril_direct",
+				},
+			},
+		},
+	}
+}
+
+func TestLastDeath(t *testing.T) {
+	tests := []struct {
+		name string
+		d    amqp.Delivery
+		ok   bool
+		want death
+	}{
+		{
+			name: "rejected",
+			d:    deadLetter("war", "war.main.alice"),
+			ok:   true,
+			want: death{reason: "rejected", queue: "war", exchange: "peril_topic", keys: []string{"war.main.alice"}, count: 2},
+		},
+		{
+			name: "no header",
+			d:    amqp.Delivery{},
+		},
+		{
+			name: "empty header",
+			d:    amqp.Delivery{Headers: amqp.Table{"x-death": []interface{}{}}},
+		},
+		{
+			name: "not a table",
+			d:    amqp.Delivery{Headers: amqp.Table{"x-death": []interface{}{"rejected"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := lastDeath(tt.d)
+			if ok != tt.ok {
+				t.Fatalf("lastDeath() ok = %v, want %v", ok, tt.ok)
+			}
+			if got.reason != tt.want.reason || got.queue != tt.want.queue || got.exchange != tt.want.exchange || got.count != tt.want.count {
+				t.Errorf("lastDeath() = %+v, want %+v", got, tt.want)
+			}
+			if len(got.keys) != len(tt.want.keys) || len(got.keys) > 0 && got.keys[0] != tt.want.keys[0] {
+				t.Errorf("lastDeath() keys = %v, want %v", got.keys, tt.want.keys)
+			}
+		})
+	}
+}
+
+func TestOrigin(t *testing.T) {
+	tests := []struct {
+		name     string
+		d        amqp.Delivery
+		exchange string
+		key      string
+	}{
+		{
+			name:     "from the x-death header",
+			d:        deadLetter("war", "war.main.alice"),
+			exchange: "peril_topic",
+			key:      "war.main.alice",
+		},
+		{
+			name:     "no header",
+			d:        amqp.Delivery{Exchange: "peril_direct", RoutingKey: "pause.main"},
+			exchange: "peril_direct",
+			key:      "pause.main",
+		},
+		{
+			name: "no routing keys",
+			d: amqp.Delivery{
+				Exchange:   "peril_dlx",
+				RoutingKey: "game_logs.main.bob",
+				Headers:    amqp.Table{"x-death": []interface{}{amqp.Table{"exchange": "peril_topic"}}},
+			},
+			exchange: "peril_dlx",
+			key:      "game_logs.main.bob",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exchange, key := origin(tt.d)
+			if exchange != tt.exchange || key != tt.key {
+				t.Errorf("origin() = %s %s, want %s %s", exchange, key, tt.exchange, tt.key)
+			}
+		})
+	}
+}
+
+func TestPickDeadLetter(t *testing.T) {
+	tests := []struct {
+		word  string
+		count int
+		want  int
+		ok    bool
+	}{
+		{"1", 3, 1, true},
+		{"3", 3, 3, true},
+		{"0", 3, 0, false},
+		{"4", 3, 0, false},
+		{"-1", 3, 0, false},
+		{"all", 3, 0, false},
+		{"1", 0, 0, false},
+	}
+	for _, tt := range tests {
+		got, err := pickDeadLetter(tt.word, tt.count)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("pickDeadLetter(%q, %d) = %d, %v, want %d, ok %v", tt.word, tt.count, got, err, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+// economy pays out territory income and keeps clients in step with the
+// balances held in the server's world.
+type economy struct {
+	ch    *amqp.Channel
+	world *gamelogic.World
+}
+
+func newEconomy(ch *amqp.Channel, world *gamelogic.World) *economy {
+	e := &economy{
+		ch:    ch,
+		world: world,
+	}
+	return e
+}
+
+func (e *economy) payout() error {
+	income := e.world.CollectIncome()
+	if len(income) == 0 {
+		return nil
+	}
+	err := recordEvent(e.ch, e.world, gamelogic.NewIncomeEvent(e.world.Game, income))
+	if err != nil {
+		return err
+	}
+	for username, amount := range income {
+		eu := routing.EconomyUpdate{
+			Balance: e.world.Balance(username),
+			Income:  amount,
+		}
+		err := e.publish(username, eu)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *economy) sync(username string) error {
+	gs := e.world.Player(username)
+	eu := routing.EconomyUpdate{
+		Balance: gs.GetBalance(),
+		Income:  gs.GetIncome(),
+	}
+	return e.publish(username, eu)
+}
+
+func (e *economy) reject(ev gamelogic.GameEvent, reason error) error {
+	eu := routing.EconomyUpdate{
+		Balance:        e.world.Balance(ev.Username),
+		RejectedUnitID: ev.Unit.ID,
+		Reason:         reason.Error(),
+	}
+	return e.publish(ev.Username, eu)
+}
+
+func (e *economy) publish(username string, eu routing.EconomyUpdate) error {
+	key := routing.Key(routing.EconomyPrefix, e.world.Game, username)
+	err := pubsub.PublishJSON(e.ch, routing.ExchangePerilDirect, key, eu)
+	if err != nil {
+		return fmt.Errorf("error publishing balance for %s: %w", username, err)
+	}
+	return nil
+}
+
+// loadWorld rebuilds a game's world from its event store, starting fresh if
+// nothing has been recorded yet.
+func loadWorld(game string) (*gamelogic.World, error) {
+	events, err := gamelogic.LoadEvents(game, time.Time{})
+	if errors.Is(err, fs.ErrNotExist) {
+		return gamelogic.NewWorld(game), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return gamelogic.Replay(game, events, time.Time{}), nil
+}
+
+// recordMu keeps the recorded events numbered, stored and published in the
+// same order.
+var recordMu sync.Mutex
+
+// recordEvent numbers ev, stores it, applies it to world and passes it on to
+// whoever follows the recorded events. Once stored the event counts, so
+// failing to pass it on is only reported.
+func recordEvent(ch pubsub.Publisher, world *gamelogic.World, ev gamelogic.GameEvent) error {
+	recordMu.Lock()
+	defer recordMu.Unlock()
+	ev.Seq = world.LastSeq() + 1
+	err := gamelogic.AppendEvent(ev)
+	if err != nil {
+		return fmt.Errorf("error recording %s event: %w", ev.Type, err)
+	}
+	world.Apply(ev)
+	key := routing.Key(routing.RecordedEventsPrefix, ev.Game)
+	err = pubsub.PublishJSON(ch, routing.ExchangePerilTopic, key, ev)
+	if err != nil {
+		fmt.Printf("error publishing recorded %s event: %v\n", ev.Type, err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/recording"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+// inTempDir runs the test in an empty directory, so the events the server
+// records go there.
+func inTempDir(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(dir) })
+}
+
+func TestRecordEvent(t *testing.T) {
+	inTempDir(t)
+	broker := recording.NewBroker()
+	defer broker.Close()
+	recorded, err := broker.Bind("spectator", routing.ExchangePerilTopic, routing.Key(routing.RecordedEventsPrefix, "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	world := gamelogic.NewWorld("test")
+	unit := gamelogic.Unit{ID: 1, Rank: gamelogic.RankInfantry, Location: "europe"}
+	err = recordEvent(broker, world, gamelogic.NewSpawnEvent("test", "alice", unit))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := world.Player("alice").GetUnit(1); !ok {
+		t.Error("the spawn wasn't applied to the world")
+	}
+	events, err := gamelogic.LoadEvents("test", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Errorf("stored %d events, want 1", len(events))
+	}
+	select {
+	case d := <-recorded:
+		var ev gamelogic.GameEvent
+		err = json.Unmarshal(d.Body, &ev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.Type != gamelogic.EventSpawn || ev.Unit == nil || ev.Unit.Location != "europe" {
+			t.Errorf("published %+v, want the spawn", ev)
+		}
+		if ev.Seq != 1 {
+			t.Errorf("published event %d, want 1", ev.Seq)
+		}
+	default:
+		t.Error("the recorded event wasn't published")
+	}
+
+	// numbering carries on after a restart
+	world, err = loadWorld("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = recordEvent(broker, world, gamelogic.NewIncomeEvent("test", map[string]int{"alice": 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err = gamelogic.LoadEvents("test", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[1].Seq != 2 {
+		t.Errorf("stored %+v, want the income as event 2", events)
+	}
+}
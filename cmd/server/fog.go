@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+// fogFlag turns on fog of war for the games restored at startup. Games
+// created later choose for themselves.
+var fogFlag = flag.Bool("fog", false, "play restored games with fog of war")
+
+// pubFogViews sends each player their own filtered view of a move. In fog of
+// war games clients don't broadcast moves, so this is the only way they
+// hear about them.
+func pubFogViews(ch *amqp.Channel, game string, views map[string]gamelogic.ArmyMove) error {
+	for username, view := range views {
+		key := routing.Key(routing.ArmyMovesPrefix, game, username)
+		err := pubsub.PublishJSON(ch, routing.ExchangePerilDirect, key, view)
+		if err != nil {
+			return fmt.Errorf("error publishing move to %s: %w", username, err)
+		}
+	}
+	return nil
+}
+
+// pubMove tells the other players about a move the server made, the way a
+// client would: to everyone, or to each player as they see it in fog of war.
+func pubMove(ch *amqp.Channel, game string, move gamelogic.ArmyMove, fog bool, views map[string]gamelogic.ArmyMove) error {
+	if fog {
+		return pubFogViews(ch, game, views)
+	}
+	key := routing.Key(routing.ArmyMovesPrefix, game, move.Player.Username)
+	return pubsub.PublishJSON(ch, routing.ExchangePerilTopic, key, move)
+}
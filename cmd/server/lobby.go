@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+// game is everything the server runs for a single game in the lobby.
+type game struct {
+	id       string
+	ch       *amqp.Channel
+	world    *gamelogic.World
+	turns    *turnManager
+	econ     *economy
+	ref      *referee
+	presence *presence
+	diplomat *diplomat
+	fog      bool
+}
+
+func (g *game) info() routing.GameInfo {
+	players := []string{}
+	for _, pi := range g.presence.online() {
+		players = append(players, pi.Username)
+	}
+	return routing.GameInfo{
+		ID:      g.id,
+		Players: players,
+		Paused:  g.world.IsPaused(),
+		Over:    g.world.IsOver(),
+		Fog:     g.fog,
+	}
+}
+
+type lobby struct {
+	mu    sync.RWMutex
+	ch    *amqp.Channel
+	games map[string]*game
+}
+
+func newLobby(ch *amqp.Channel) *lobby {
+	return &lobby{
+		ch:    ch,
+		games: map[string]*game{},
+	}
+}
+
+// restore brings back every game with recorded events, plus the default game.
+func (l *lobby) restore() error {
+	ids, err := gamelogic.StoredGames()
+	if err != nil {
+		return fmt.Errorf("error listing stored games: %w", err)
+	}
+	ids = append(ids, routing.DefaultGame)
+	for _, id := range ids {
+		if _, ok := l.get(id); ok {
+			continue
+		}
+		_, err := l.create(id, *fogFlag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *lobby) create(id string, fog bool) (*game, error) {
+	err := routing.ValidateGameID(id)
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.games[id]; ok {
+		return nil, fmt.Errorf("game %s already exists", id)
+	}
+
+	world, err := loadWorld(id)
+	if err != nil {
+		return nil, fmt.Errorf("error loading events for game %s: %w", id, err)
+	}
+	vc, err := victoryConditions(l.ch, world)
+	if err != nil {
+		return nil, fmt.Errorf("error setting victory conditions for game %s: %w", id, err)
+	}
+	g := &game{
+		id:    id,
+		ch:    l.ch,
+		world: world,
+		turns: newTurnManager(l.ch, id),
+		econ:  newEconomy(l.ch, world),
+		fog:   fog,
+	}
+	g.ref = newReferee(l.ch, world, g.econ, g.turns, vc)
+	g.diplomat = newDiplomat(l.ch, world)
+	g.turns.carryOut = g.carryOut
+	g.presence = newPresence(l.ch, world, g.turns, g.diplomat)
+	g.diplomat.inGame = g.presence.isOnline
+	go g.ref.run()
+	go g.presence.run()
+	go g.diplomat.run()
+	l.games[id] = g
+	fmt.Printf("Created game %s, victory conditions: %v\n", id, g.ref.victory.Conditions)
+	if fog {
+		fmt.Printf("Game %s is played with fog of war\n", id)
+	}
+	return g, nil
+}
+
+func (l *lobby) get(id string) (*game, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	g, ok := l.games[id]
+	return g, ok
+}
+
+func (l *lobby) list() []routing.GameInfo {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	games := []routing.GameInfo{}
+	for _, g := range l.games {
+		games = append(games, g.info())
+	}
+	sort.Slice(games, func(i, j int) bool { return games[i].ID < games[j].ID })
+	return games
+}
+
+func (l *lobby) join(id, username string) error {
+	err := routing.ValidateUsername(username)
+	if err != nil {
+		return err
+	}
+	g, ok := l.get(id)
+	if !ok {
+		return fmt.Errorf("game %s does not exist", id)
+	}
+	if g.world.IsOver() {
+		return fmt.Errorf("game %s is over", id)
+	}
+	return g.presence.touch(username)
+}
+
+func (l *lobby) leave(id, username string) error {
+	g, ok := l.get(id)
+	if !ok {
+		return fmt.Errorf("game %s does not exist", id)
+	}
+	return g.presence.leave(username, "left the game")
+}
+
+func handlerLobby(l *lobby) func(routing.LobbyRequest) pubsub.AckType {
+	f := func(req routing.LobbyRequest) pubsub.AckType {
+		handled.inc(routing.LobbyKey)
+		defer fmt.Print("> ")
+		resp := routing.LobbyResponse{
+			Action: req.Action,
+			Game:   req.Game,
+		}
+		var err error
+		switch req.Action {
+		case routing.LobbyList:
+		case routing.LobbyCreate:
+			// check the username before there's a game they can't join
+			err = routing.ValidateUsername(req.Username)
+			if err == nil {
+				_, err = l.create(req.Game, req.Fog)
+			}
+			if err == nil {
+				err = l.join(req.Game, req.Username)
+			}
+		case routing.LobbyJoin:
+			err = l.join(req.Game, req.Username)
+		case routing.LobbyLeave:
+			err = l.leave(req.Game, req.Username)
+		default:
+			err = fmt.Errorf("unknown lobby action: %s", req.Action)
+		}
+		if err != nil {
+			resp.Error = err.Error()
+		} else if req.Action != routing.LobbyList {
+			fmt.Printf("%s: %s %s\n", req.Username, req.Action, req.Game)
+		}
+		resp.Games = l.list()
+
+		key := routing.Key(routing.LobbyKey, req.Username)
+		err = pubsub.PublishJSON(l.ch, routing.ExchangePerilDirect, key, resp)
+		if err != nil {
+			fmt.Printf("error answering lobby request: %v\n", err)
+			return pubsub.NackRequeue
+		}
+		return pubsub.Ack
+	}
+	return f
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// handled counts the messages each subscription has handled, by queue.
+var handled = &counter{counts: map[string]int64{}}
+
+type counter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (c *counter) inc(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[name]++
+}
+
+func (c *counter) snapshot() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := map[string]int64{}
+	for name, n := range c.counts {
+		counts[name] = n
+	}
+	return counts
+}
+
+// writeMetrics writes the server's metrics in the Prometheus text format.
+func writeMetrics(w io.Writer, api *adminAPI) {
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	metric("peril_uptime_seconds", "gauge", "Seconds since the server started.")
+	fmt.Fprintf(w, "peril_uptime_seconds %.0f\n", time.Since(api.started).Seconds())
+
+	metric("peril_broker_connected", "gauge", "Whether the server is connected to the broker.")
+	fmt.Fprintf(w, "peril_broker_connected %d\n", boolMetric(!api.conn.IsClosed()))
+
+	counts := handled.snapshot()
+	queues := []string{}
+	for queue := range counts {
+		queues = append(queues, queue)
+	}
+	sort.Strings(queues)
+	metric("peril_messages_handled_total", "counter", "Messages handled, by queue.")
+	for _, queue := range queues {
+		fmt.Fprintf(w, "peril_messages_handled_total{queue=%q} %d\n", queue, counts[queue])
+	}
+
+	games := api.games.list()
+	metric("peril_games", "gauge", "Games on the server.")
+	fmt.Fprintf(w, "peril_games %d\n", len(games))
+	metric("peril_players_online", "gauge", "Players online, by game.")
+	for _, info := range games {
+		fmt.Fprintf(w, "peril_players_online{game=%q} %d\n", info.ID, len(info.Players))
+	}
+	metric("peril_game_paused", "gauge", "Whether a game is paused.")
+	for _, info := range games {
+		fmt.Fprintf(w, "peril_game_paused{game=%q} %d\n", info.ID, boolMetric(info.Paused))
+	}
+
+	n, err := api.dlq.count()
+	if err == nil {
+		metric("peril_dead_letters", "gauge", "Messages waiting in the dead letter queue.")
+		fmt.Fprintf(w, "peril_dead_letters %d\n", n)
+	}
+}
+
+func boolMetric(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+// presence tracks which players are connected to a game. A player who stops
+// sending heartbeats is dropped after routing.HeartbeatTimeout and their
+// units are taken off the map. Kicked players are kept out for good.
+type presence struct {
+	mu       sync.Mutex
+	ch       pubsub.Publisher
+	world    *gamelogic.World
+	turns    *turnManager
+	diplomat *diplomat
+	lastSeen map[string]time.Time
+	kicked   map[string]bool
+}
+
+type playerInfo struct {
+	Username string
+	LastSeen time.Time
+	Units    int
+}
+
+func newPresence(ch pubsub.Publisher, world *gamelogic.World, turns *turnManager, diplomat *diplomat) *presence {
+	return &presence{
+		ch:       ch,
+		world:    world,
+		turns:    turns,
+		diplomat: diplomat,
+		lastSeen: map[string]time.Time{},
+		kicked:   map[string]bool{},
+	}
+}
+
+func (p *presence) run() {
+	ticker := time.NewTicker(routing.HeartbeatInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		for _, username := range p.expired(time.Now()) {
+			err := p.leave(username, "timed out")
+			if err != nil {
+				fmt.Printf("error dropping %s: %v\n", username, err)
+			}
+		}
+	}
+}
+
+func (p *presence) expired(now time.Time) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	expired := []string{}
+	for username, seen := range p.lastSeen {
+		if now.Sub(seen) > routing.HeartbeatTimeout {
+			expired = append(expired, username)
+		}
+	}
+	return expired
+}
+
+// touch records that username is alive, announcing them if they weren't
+// online yet.
+func (p *presence) touch(username string) error {
+	p.mu.Lock()
+	if p.kicked[username] {
+		p.mu.Unlock()
+		return fmt.Errorf("%s was kicked from game %s", username, p.world.Game)
+	}
+	_, online := p.lastSeen[username]
+	p.lastSeen[username] = time.Now()
+	p.mu.Unlock()
+	p.turns.seen(username)
+	if online {
+		return nil
+	}
+
+	fmt.Printf("[%s] %s joined\n", p.world.Game, username)
+	pj := routing.PlayerJoined{
+		Game:     p.world.Game,
+		Username: username,
+		Time:     time.Now(),
+	}
+	key := routing.Key(routing.PlayerJoinedPrefix, p.world.Game)
+	err := pubsub.PublishJSON(p.ch, routing.ExchangePerilTopic, key, pj)
+	if err != nil {
+		return fmt.Errorf("error publishing player joined: %w", err)
+	}
+	return nil
+}
+
+// leave takes username out of the game and removes their units. Leaving a
+// game you're not in does nothing, since a client announces that it left
+// both to the lobby and in its presence messages.
+func (p *presence) leave(username, reason string) error {
+	p.mu.Lock()
+	_, online := p.lastSeen[username]
+	delete(p.lastSeen, username)
+	p.mu.Unlock()
+	if !online {
+		return nil
+	}
+	p.turns.forget(username)
+	p.diplomat.forget(username)
+
+	fmt.Printf("[%s] %s left (%s)\n", p.world.Game, username, reason)
+	err := recordEvent(p.ch, p.world, gamelogic.NewPlayerLeftEvent(p.world.Game, username))
+	if err != nil {
+		return err
+	}
+	pl := routing.PlayerLeft{
+		Game:     p.world.Game,
+		Username: username,
+		Reason:   reason,
+		Time:     time.Now(),
+	}
+	key := routing.Key(routing.PlayerLeftPrefix, p.world.Game)
+	err = pubsub.PublishJSON(p.ch, routing.ExchangePerilTopic, key, pl)
+	if err != nil {
+		return fmt.Errorf("error publishing player left: %w", err)
+	}
+	return nil
+}
+
+// kick takes username out of the game and refuses their heartbeats and
+// joins from then on, so a client that is slow to notice can't come back.
+func (p *presence) kick(username string) error {
+	p.mu.Lock()
+	p.kicked[username] = true
+	p.mu.Unlock()
+	return p.leave(username, "kicked")
+}
+
+func (p *presence) isOnline(username string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, online := p.lastSeen[username]
+	return online
+}
+
+func (p *presence) isKicked(username string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.kicked[username]
+}
+
+func (p *presence) online() []playerInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	players := []playerInfo{}
+	for username, seen := range p.lastSeen {
+		players = append(players, playerInfo{
+			Username: username,
+			LastSeen: seen,
+			Units:    p.world.UnitCount(username),
+		})
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].Username < players[j].Username })
+	return players
+}
+
+func commandPlayers(p *presence) {
+	players := p.online()
+	if len(players) == 0 {
+		fmt.Printf("No players are online in %s.\n", p.world.Game)
+		return
+	}
+	fmt.Printf("Players online in %s:\n", p.world.Game)
+	for _, pi := range players {
+		fmt.Printf("* %s: last seen %v ago, %d units\n", pi.Username, time.Since(pi.LastSeen).Round(time.Second), pi.Units)
+	}
+}
+
+func handlerPresence(l *lobby) func(routing.Presence) pubsub.AckType {
+	f := func(pr routing.Presence) pubsub.AckType {
+		handled.inc(routing.PresencePrefix)
+		g, ok := l.get(pr.Game)
+		if !ok {
+			return pubsub.NackDiscard
+		}
+		var err error
+		switch pr.Status {
+		case routing.PresenceJoin, routing.PresenceHeartbeat:
+			err = g.presence.touch(pr.Username)
+		case routing.PresenceLeave:
+			err = g.presence.leave(pr.Username, "left the game")
+		default:
+			err = fmt.Errorf("unknown presence status: %s", pr.Status)
+		}
+		if err != nil {
+			fmt.Printf("error handling presence from %s: %v\n", pr.Username, err)
+			fmt.Print("> ")
+			return pubsub.NackDiscard
+		}
+		return pubsub.Ack
+	}
+	return f
+}
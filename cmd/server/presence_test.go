@@ -0,0 +1,126 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/recording"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+func TestOnlineDoesNotAddPlayers(t *testing.T) {
+	world := gamelogic.Replay("test", []gamelogic.GameEvent{
+		gamelogic.NewSpawnEvent("test", "alice", gamelogic.Unit{ID: 1, Rank: gamelogic.RankInfantry, Location: "europe"}),
+	}, time.Time{})
+	p := newPresence(nil, world, newTurnManager(nil, "test"), nil)
+	p.lastSeen["alice"] = time.Now()
+	p.lastSeen["bob"] = time.Now()
+
+	players := p.online()
+	if len(players) != 2 || players[0].Units != 1 || players[1].Units != 0 {
+		t.Errorf("online() = %+v, want alice with 1 unit and bob with none", players)
+	}
+	if got := world.Usernames(); len(got) != 1 || got[0] != "alice" {
+		t.Errorf("world players = %v after listing who's online, want only alice", got)
+	}
+}
+
+func TestKick(t *testing.T) {
+	tests := []struct {
+		name   string
+		online bool
+		kick   string
+		touch  string
+		wantOK bool
+	}{
+		{name: "kicked player can't come back", online: true, kick: "bob", touch: "bob"},
+		{name: "kicked while offline", kick: "bob", touch: "bob"},
+		{name: "others stay", online: true, kick: "bob", touch: "alice", wantOK: true},
+		{name: "newcomers can join", online: true, kick: "bob", touch: "carol", wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inTempDir(t)
+			broker := recording.NewBroker()
+			defer broker.Close()
+			world := gamelogic.NewWorld("test")
+			p := newPresence(broker, world, newTurnManager(nil, "test"), newDiplomat(broker, world))
+			p.lastSeen["alice"] = time.Now()
+			if tt.online {
+				p.lastSeen["bob"] = time.Now()
+			}
+
+			err := p.kick(tt.kick)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := p.lastSeen[tt.kick]; ok {
+				t.Errorf("%s is still online after being kicked", tt.kick)
+			}
+			if !p.isKicked(tt.kick) {
+				t.Errorf("%s isn't marked as kicked", tt.kick)
+			}
+			err = p.touch(tt.touch)
+			if (err == nil) != tt.wantOK {
+				t.Errorf("touch(%s) = %v, want ok %v", tt.touch, err, tt.wantOK)
+			}
+			if _, ok := p.lastSeen[tt.touch]; ok != tt.wantOK {
+				t.Errorf("%s online = %v after touch, want %v", tt.touch, ok, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		lastSeen time.Duration
+		expired  bool
+	}{
+		{name: "just seen", lastSeen: 0},
+		{name: "a missed heartbeat", lastSeen: routing.HeartbeatInterval + time.Second},
+		{name: "right at the timeout", lastSeen: routing.HeartbeatTimeout},
+		{name: "past the timeout", lastSeen: routing.HeartbeatTimeout + time.Second, expired: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPresence(nil, gamelogic.NewWorld("test"), newTurnManager(nil, "test"), nil)
+			p.lastSeen["alice"] = now.Add(-tt.lastSeen)
+			got := p.expired(now)
+			if (len(got) == 1) != tt.expired {
+				t.Errorf("expired() = %v, want alice expired %v", got, tt.expired)
+			}
+		})
+	}
+}
+
+func TestLobbyJoinAfterKick(t *testing.T) {
+	inTempDir(t)
+	broker := recording.NewBroker()
+	defer broker.Close()
+	world := gamelogic.NewWorld("test")
+	p := newPresence(broker, world, newTurnManager(nil, "test"), newDiplomat(broker, world))
+	l := newLobby(nil)
+	l.games["test"] = &game{id: "test", world: world, presence: p}
+
+	err := p.kick("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		username string
+		ok       bool
+	}{
+		{"bob", false},
+		{"alice", true},
+		{"bob", false},
+	}
+	for _, tt := range tests {
+		err := l.join("test", tt.username)
+		if (err == nil) != tt.ok {
+			t.Errorf("join(%s) = %v, want ok %v", tt.username, err, tt.ok)
+		}
+	}
+}
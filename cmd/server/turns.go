@@ -0,0 +1,339 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+const defaultTurnLength = 60 * time.Second
+
+// turnManager drives turn-based mode. It announces each turn, collects the
+// orders players submit and resolves them together when the deadline passes
+// or every known player has submitted.
+type turnManager struct {
+	mu        sync.Mutex
+	ch        *amqp.Channel
+	game      string
+	enabled   bool
+	paused    bool
+	length    time.Duration
+	number    int
+	deadline  time.Time
+	remaining time.Duration
+	timer     *time.Timer
+	players   map[string]struct{}
+	orders    map[string][][]string
+	// carryOut resolves the orders against the world, returning what was
+	// recorded and the orders it turned down.
+	carryOut  func(orders map[string][][]string) ([]gamelogic.GameEvent, map[string][]string)
+	onResolve func()
+}
+
+func newTurnManager(ch *amqp.Channel, game string) *turnManager {
+	return &turnManager{
+		ch:      ch,
+		game:    game,
+		players: map[string]struct{}{},
+		orders:  map[string][][]string{},
+	}
+}
+
+// seen marks a player as taking part, so their orders are waited for.
+func (tm *turnManager) seen(username string) {
+	if username == "" {
+		return
+	}
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.players[username] = struct{}{}
+}
+
+// forget stops waiting for orders from a player who left.
+func (tm *turnManager) forget(username string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	delete(tm.players, username)
+	delete(tm.orders, username)
+	if tm.enabled && !tm.paused && tm.allSubmittedLocked() {
+		err := tm.resolveLocked(false)
+		if err != nil {
+			fmt.Printf("error resolving turn %d: %v\n", tm.number, err)
+		}
+	}
+}
+
+func (tm *turnManager) isEnabled() bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return tm.enabled
+}
+
+func (tm *turnManager) start(length time.Duration) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.enabled {
+		return fmt.Errorf("turn-based mode is already running (turn %d)", tm.number)
+	}
+	tm.enabled = true
+	tm.length = length
+	return tm.startTurnLocked(tm.number + 1)
+}
+
+func (tm *turnManager) stop() error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if !tm.enabled {
+		return fmt.Errorf("turn-based mode is not running")
+	}
+	return tm.resolveLocked(true)
+}
+
+// setPaused freezes the turn timer while the game is paused, and restarts it
+// with the time that was left on resume.
+func (tm *turnManager) setPaused(paused bool) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.paused == paused {
+		return nil
+	}
+	tm.paused = paused
+	if !tm.enabled {
+		return nil
+	}
+	if paused {
+		tm.timer.Stop()
+		tm.remaining = time.Until(tm.deadline)
+		return nil
+	}
+	tm.deadline = time.Now().Add(tm.remaining)
+	tm.timer = time.AfterFunc(tm.remaining, tm.expire(tm.number))
+	if tm.allSubmittedLocked() {
+		return tm.resolveLocked(false)
+	}
+	return tm.publishTurnLocked()
+}
+
+func (tm *turnManager) submit(to routing.TurnOrders) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.players[to.Username] = struct{}{}
+	if !tm.enabled {
+		return fmt.Errorf("%s submitted orders, but turn-based mode is not running", to.Username)
+	}
+	if to.Number != tm.number {
+		return fmt.Errorf("%s submitted orders for turn %d, but it is turn %d", to.Username, to.Number, tm.number)
+	}
+	if _, ok := tm.orders[to.Username]; ok {
+		return fmt.Errorf("%s already submitted orders for turn %d", to.Username, tm.number)
+	}
+	tm.orders[to.Username] = to.Orders
+	fmt.Printf("[%s] %s submitted %d order(s) for turn %d\n", tm.game, to.Username, len(to.Orders), tm.number)
+	if !tm.paused && tm.allSubmittedLocked() {
+		return tm.resolveLocked(false)
+	}
+	return nil
+}
+
+func (tm *turnManager) expire(number int) func() {
+	return func() {
+		tm.mu.Lock()
+		defer tm.mu.Unlock()
+		if !tm.enabled || tm.paused || tm.number != number {
+			return
+		}
+		err := tm.resolveLocked(false)
+		if err != nil {
+			fmt.Printf("error resolving turn %d: %v\n", number, err)
+		}
+	}
+}
+
+func (tm *turnManager) allSubmittedLocked() bool {
+	if len(tm.players) == 0 {
+		return false
+	}
+	for username := range tm.players {
+		if _, ok := tm.orders[username]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveLocked carries out the turn's orders and tells each player how
+// theirs went.
+func (tm *turnManager) resolveLocked(final bool) error {
+	tm.timer.Stop()
+	orders := tm.orders
+	tm.orders = map[string][][]string{}
+	var events []gamelogic.GameEvent
+	var rejected map[string][]string
+	if tm.carryOut != nil {
+		events, rejected = tm.carryOut(orders)
+	}
+
+	submitted := map[string]int{}
+	for username, o := range orders {
+		submitted[username] = len(o)
+	}
+	usernames := []string{}
+	for username := range tm.players {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+	for _, username := range usernames {
+		tr := gamelogic.TurnResolved{
+			Number:    tm.number,
+			Submitted: submitted,
+			Rejected:  rejected[username],
+			Final:     final,
+		}
+		for _, ev := range events {
+			if ev.Username == username && (ev.Type == gamelogic.EventSpawn || ev.Type == gamelogic.EventMove) {
+				tr.Events = append(tr.Events, ev)
+			}
+		}
+		key := routing.Key(routing.TurnResolvedKey, tm.game, username)
+		err := pubsub.PublishJSON(tm.ch, routing.ExchangePerilDirect, key, tr)
+		if err != nil {
+			return fmt.Errorf("error publishing turn resolution to %s: %w", username, err)
+		}
+	}
+	fmt.Printf("[%s] Turn %d resolved with orders from %d player(s), %d event(s) recorded\n", tm.game, tm.number, len(orders), len(events))
+	if tm.onResolve != nil {
+		go tm.onResolve()
+	}
+	if final {
+		tm.enabled = false
+		fmt.Printf("[%s] Turn-based mode stopped.\n", tm.game)
+		return nil
+	}
+	return tm.startTurnLocked(tm.number + 1)
+}
+
+func (tm *turnManager) startTurnLocked(number int) error {
+	tm.number = number
+	tm.remaining = tm.length
+	tm.deadline = time.Now().Add(tm.length)
+	tm.timer = time.AfterFunc(tm.length, tm.expire(number))
+	if tm.paused {
+		tm.timer.Stop()
+	}
+	return tm.publishTurnLocked()
+}
+
+func (tm *turnManager) publishTurnLocked() error {
+	ts := routing.TurnStarted{
+		Number:   tm.number,
+		Deadline: tm.deadline,
+	}
+	if tm.paused {
+		ts.Deadline = time.Now().Add(tm.remaining)
+	}
+	key := routing.Key(routing.TurnKey, tm.game)
+	err := pubsub.PublishJSON(tm.ch, routing.ExchangePerilDirect, key, ts)
+	if err != nil {
+		return fmt.Errorf("error publishing turn start: %w", err)
+	}
+	fmt.Printf("[%s] Turn %d started, deadline %s\n", tm.game, ts.Number, ts.Deadline.Format(time.TimeOnly))
+	return nil
+}
+
+// carryOut resolves a turn's orders against the game's world. Every event is
+// recorded as it happens; the other players hear about each move as they
+// would in real time, and both sides of a war get its result.
+func (g *game) carryOut(orders map[string][][]string) ([]gamelogic.GameEvent, map[string][]string) {
+	if g.world.IsOver() {
+		return nil, nil
+	}
+	events := []gamelogic.GameEvent{}
+	atPeace := func(a, b string) bool {
+		return g.diplomat.diplomacy.AtPeace(a, b, time.Now())
+	}
+	record := func(ev gamelogic.GameEvent) error {
+		var views map[string]gamelogic.ArmyMove
+		if g.fog && ev.Type == gamelogic.EventMove {
+			views = g.world.FogViews(*ev.Move)
+		}
+		err := recordEvent(g.ch, g.world, ev)
+		if err != nil {
+			return err
+		}
+		events = append(events, ev)
+		switch ev.Type {
+		case gamelogic.EventMove:
+			err = pubMove(g.ch, g.id, *ev.Move, g.fog, views)
+		case gamelogic.EventWarOutcome:
+			err = pubWarResult(g.ch, ev)
+		}
+		if err != nil {
+			fmt.Printf("error publishing %s event: %v\n", ev.Type, err)
+		}
+		return nil
+	}
+	rejected, err := g.world.ResolveTurn(orders, atPeace, record)
+	if err != nil {
+		fmt.Printf("error resolving orders for game %s: %v\n", g.id, err)
+	}
+
+	spent := map[string]bool{}
+	for _, ev := range events {
+		if ev.Type == gamelogic.EventSpawn && !spent[ev.Username] {
+			spent[ev.Username] = true
+			err = g.econ.sync(ev.Username)
+			if err != nil {
+				fmt.Printf("error syncing balance: %v\n", err)
+			}
+		}
+	}
+	return events, rejected
+}
+
+func commandTurns(tm *turnManager, words []string) error {
+	if len(words) < 2 {
+		return fmt.Errorf("usage: turns start [seconds] | turns stop")
+	}
+	switch words[1] {
+	case "start":
+		length := defaultTurnLength
+		if len(words) > 2 {
+			d, err := time.ParseDuration(words[2] + "s")
+			if err != nil || d <= 0 {
+				return fmt.Errorf("can't use %s as a number of seconds", words[2])
+			}
+			length = d
+		}
+		return tm.start(length)
+	case "stop":
+		return tm.stop()
+	default:
+		return fmt.Errorf("usage: turns start [seconds] | turns stop")
+	}
+}
+
+func handlerTurnOrders(l *lobby) func(routing.TurnOrders) pubsub.AckType {
+	f := func(to routing.TurnOrders) pubsub.AckType {
+		handled.inc(routing.TurnOrdersKey)
+		defer fmt.Print("> ")
+		g, ok := l.get(to.Game)
+		if !ok {
+			fmt.Printf("rejected orders from %s: game %s does not exist\n", to.Username, to.Game)
+			return pubsub.NackDiscard
+		}
+		err := g.turns.submit(to)
+		if err != nil {
+			fmt.Printf("rejected orders: %v\n", err)
+			return pubsub.NackDiscard
+		}
+		return pubsub.Ack
+	}
+	return f
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+var (
+	victoryTerritories = flag.Int("victory-territories", 0, "win by holding this many territories (needs -victory-hold)")
+	victoryHold        = flag.Int("victory-hold", 0, "number of ticks the territories must be held for")
+	victoryElimination = flag.Bool("victory-elimination", false, "win by eliminating all opponents")
+	victoryDeadline    = flag.Duration("victory-deadline", 0, "end the game after this long; the highest score wins")
+)
+
+// victoryConditions are the flags' conditions for world. The deadline is
+// recorded when the game is first created and kept from then on, so
+// restarting the server doesn't push it back.
+func victoryConditions(ch pubsub.Publisher, world *gamelogic.World) (gamelogic.VictoryConditions, error) {
+	vc := gamelogic.VictoryConditions{
+		HoldTerritories: *victoryTerritories,
+		HoldTicks:       *victoryHold,
+		Elimination:     *victoryElimination,
+		Deadline:        world.VictoryDeadline(),
+	}
+	if vc.Deadline.IsZero() && *victoryDeadline > 0 {
+		ev := gamelogic.NewDeadlineEvent(world.Game, time.Now().Add(*victoryDeadline))
+		err := recordEvent(ch, world, ev)
+		if err != nil {
+			return vc, err
+		}
+		vc.Deadline = *ev.Deadline
+	}
+	return vc, nil
+}
+
+// referee advances the game clock: every tick (or turn, in turn-based mode)
+// it pays out income and checks whether anyone has won.
+type referee struct {
+	mu      sync.Mutex
+	ch      *amqp.Channel
+	world   *gamelogic.World
+	econ    *economy
+	turns   *turnManager
+	victory *gamelogic.VictoryTracker
+}
+
+func newReferee(ch *amqp.Channel, world *gamelogic.World, econ *economy, turns *turnManager, vc gamelogic.VictoryConditions) *referee {
+	r := &referee{
+		ch:      ch,
+		world:   world,
+		econ:    econ,
+		turns:   turns,
+		victory: gamelogic.NewVictoryTracker(vc),
+	}
+	turns.onResolve = r.tick
+	return r
+}
+
+func (r *referee) run() {
+	ticker := time.NewTicker(gamelogic.EconomyTick)
+	defer ticker.Stop()
+	for range ticker.C {
+		if r.world.IsPaused() || r.turns.isEnabled() {
+			continue
+		}
+		r.tick()
+	}
+}
+
+func (r *referee) tick() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.world.IsOver() {
+		return
+	}
+	err := r.econ.payout()
+	if err != nil {
+		fmt.Printf("error paying income: %v\n", err)
+	}
+	over, ok := r.victory.Evaluate(r.world, time.Now())
+	if !ok {
+		return
+	}
+	err = r.endGame(over)
+	if err != nil {
+		fmt.Printf("error ending game: %v\n", err)
+	}
+}
+
+func (r *referee) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.victory.Reset()
+}
+
+func (r *referee) endGame(over routing.GameOver) error {
+	err := recordEvent(r.ch, r.world, gamelogic.NewGameOverEvent(r.world.Game, over))
+	if err != nil {
+		return err
+	}
+	if r.turns.isEnabled() {
+		err = r.turns.stop()
+		if err != nil {
+			return err
+		}
+	}
+	key := routing.Key(routing.GameOverKey, r.world.Game)
+	err = pubsub.PublishJSON(r.ch, routing.ExchangePerilDirect, key, over)
+	if err != nil {
+		return fmt.Errorf("error publishing game over: %w", err)
+	}
+	fmt.Println()
+	fmt.Printf("==== Game Over: %s ====\n", r.world.Game)
+	fmt.Printf("The game ended because %s.\n", over.Reason)
+	gamelogic.PrintStandings(over.Standings)
+	fmt.Print("> ")
+	return nil
+}
+
+func commandStandings(r *referee) {
+	fmt.Printf("Victory conditions: %v\n", r.victory.Conditions)
+	gamelogic.PrintStandings(r.world.Standings())
+}
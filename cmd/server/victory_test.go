@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/recording"
+)
+
+// TestVictoryDeadlineRestored checks that restarting the server, which
+// loads the game again, keeps the deadline it was created with.
+func TestVictoryDeadlineRestored(t *testing.T) {
+	inTempDir(t)
+	old := *victoryDeadline
+	t.Cleanup(func() { *victoryDeadline = old })
+	*victoryDeadline = time.Hour
+	broker := recording.NewBroker()
+	defer broker.Close()
+
+	world, err := loadWorld("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := victoryConditions(broker, world)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if until := time.Until(created.Deadline); until <= 0 || until > time.Hour {
+		t.Fatalf("new game's deadline is %v away, want within the hour", until)
+	}
+
+	world, err = loadWorld("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := victoryConditions(broker, world)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !restored.Deadline.Equal(created.Deadline) {
+		t.Errorf("restored deadline = %v, want %v", restored.Deadline, created.Deadline)
+	}
+}
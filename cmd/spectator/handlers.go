@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+func handlerGameEvent(sp *spectator) func(gamelogic.GameEvent) pubsub.AckType {
+	f := func(ev gamelogic.GameEvent) pubsub.AckType {
+		sp.receive(ev)
+		return pubsub.Ack
+	}
+	return f
+}
+
+func handlerPause(sp *spectator) func(routing.PlayingState) pubsub.AckType {
+	f := func(ps routing.PlayingState) pubsub.AckType {
+		sp.report(kindPause, describePause(ps))
+		return pubsub.Ack
+	}
+	return f
+}
+
+func handlerPlayerJoined(sp *spectator) func(routing.PlayerJoined) pubsub.AckType {
+	f := func(pj routing.PlayerJoined) pubsub.AckType {
+		sp.report(kindPlayer, fmt.Sprintf("%s joined the game", pj.Username))
+		return pubsub.Ack
+	}
+	return f
+}
+
+func handlerPlayerLeft(sp *spectator) func(routing.PlayerLeft) pubsub.AckType {
+	f := func(pl routing.PlayerLeft) pubsub.AckType {
+		text := fmt.Sprintf("%s left the game (%s)", pl.Username, pl.Reason)
+		sp.report(kindPlayer, text)
+		return pubsub.Ack
+	}
+	return f
+}
+
+func handlerGameOver(sp *spectator) func(routing.GameOver) pubsub.AckType {
+	f := func(o routing.GameOver) pubsub.AckType {
+		sp.report(kindGame, describeGameOver(o))
+		return pubsub.Ack
+	}
+	return f
+}
+
+func handlerAdmin(sp *spectator) func(routing.AdminMessage) pubsub.AckType {
+	f := func(msg routing.AdminMessage) pubsub.AckType {
+		switch msg.Kind {
+		case routing.AdminReset:
+			sp.report(kindAdmin, "The game was reset")
+		case routing.AdminBroadcast:
+			sp.report(kindAdmin, "Server: "+msg.Message)
+		case routing.AdminKick:
+			sp.report(kindAdmin, fmt.Sprintf("%s was kicked", msg.Target))
+		}
+		return pubsub.Ack
+	}
+	return f
+}
+
+func handlerGameLog(sp *spectator) func(routing.GameLog) pubsub.AckType {
+	f := func(gl routing.GameLog) pubsub.AckType {
+		sp.report(kindLog, fmt.Sprintf("%s: %s", gl.Username, gl.Message))
+		return pubsub.Ack
+	}
+	return f
+}
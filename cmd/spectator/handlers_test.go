@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+// TestWorldFollowsRecordedEvents checks that only the recorded events
+// change the spectator's world; the other messages only go in the feed.
+func TestWorldFollowsRecordedEvents(t *testing.T) {
+	sp := newSpectator("test", gamelogic.NewWorld("test"))
+	recorded := handlerGameEvent(sp)
+
+	unit := gamelogic.Unit{ID: 1, Rank: gamelogic.RankInfantry, Location: "europe"}
+	recorded(gamelogic.NewSpawnEvent("test", "alice", unit))
+	recorded(gamelogic.NewIncomeEvent("test", map[string]int{"alice": 3}))
+	if got := sp.world.Player("alice").GetBalance(); got != gamelogic.StartingBalance+1 {
+		t.Errorf("alice's balance = %d, want %d", got, gamelogic.StartingBalance+1)
+	}
+	if len(sp.feed) != 1 {
+		t.Errorf("feed = %+v, want only the spawn", sp.feed)
+	}
+
+	handlerPause(sp)(routing.PlayingState{IsPaused: true})
+	if sp.world.IsPaused() {
+		t.Error("the pause message paused the world before the server recorded it")
+	}
+	recorded(gamelogic.NewPauseEvent("test", true))
+	if !sp.world.IsPaused() {
+		t.Error("the recorded pause didn't pause the world")
+	}
+
+	handlerPlayerLeft(sp)(routing.PlayerLeft{Username: "alice", Reason: "timed out"})
+	if _, ok := sp.world.Player("alice").GetUnit(1); !ok {
+		t.Error("the player left message took alice's units before the server recorded it")
+	}
+	recorded(gamelogic.NewPlayerLeftEvent("test", "alice"))
+	if _, ok := sp.world.Player("alice").GetUnit(1); ok {
+		t.Error("the recorded player left event didn't take alice's units")
+	}
+}
+
+// TestCatchUp checks the recorded events that come in while the stored ones
+// are loaded are applied once, after them.
+func TestCatchUp(t *testing.T) {
+	numbered := func(seq int, ev gamelogic.GameEvent) gamelogic.GameEvent {
+		ev.Seq = seq
+		return ev
+	}
+	unit := gamelogic.Unit{ID: 1, Rank: gamelogic.RankInfantry, Location: "europe"}
+	spawn := numbered(1, gamelogic.NewSpawnEvent("test", "alice", unit))
+	income := func(seq int) gamelogic.GameEvent {
+		return numbered(seq, gamelogic.NewIncomeEvent("test", map[string]int{"alice": 3}))
+	}
+
+	sp := newSpectator("test", gamelogic.NewWorld("test"))
+	sp.buffer()
+	recorded := handlerGameEvent(sp)
+	// recorded while the stored events were read: 2 made it into the file,
+	// 3 didn't
+	recorded(income(2))
+	recorded(income(3))
+	if got := sp.world.LastSeq(); got != 0 {
+		t.Fatalf("the world is at event %d before catching up, want 0", got)
+	}
+
+	sp.catchUp([]gamelogic.GameEvent{spawn, income(2)})
+	want := gamelogic.StartingBalance - 2 + 3 + 3
+	if got := sp.world.Player("alice").GetBalance(); got != want {
+		t.Errorf("alice's balance = %d, want %d: each income once", got, want)
+	}
+	if got := sp.world.LastSeq(); got != 3 {
+		t.Errorf("the world is at event %d, want 3", got)
+	}
+
+	recorded(income(4))
+	if got := sp.world.LastSeq(); got != 4 {
+		t.Errorf("the world is at event %d after catching up, want 4", got)
+	}
+}
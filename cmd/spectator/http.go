@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// keepAlive is how often an idle event stream gets a comment, so proxies
+// don't close it.
+const keepAlive = 15 * time.Second
+
+func (sp *spectator) handleState(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(sp.view())
+	if err != nil {
+		fmt.Printf("error writing state: %v\n", err)
+	}
+}
+
+// handleEvents streams the game to the page as server-sent events.
+func (sp *spectator) handleEvents(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	ch := sp.watch()
+	defer sp.unwatch(ch)
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		var err error
+		select {
+		case msg := <-ch:
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.event, msg.data)
+		case <-ticker.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/config"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+var (
+	gameID   = flag.String("game", routing.DefaultGame, "game to watch")
+	httpAddr = flag.String("http", ":8081", "address to serve the dashboard on")
+)
+
+var configFlags = config.RegisterFlags(flag.CommandLine)
+
+//go:embed static
+var static embed.FS
+
+func main() {
+	flag.Parse()
+	cfg, err := config.Load(configFlags)
+	if err != nil {
+		fmt.Printf("error loading config: %v\n", err)
+		return
+	}
+	conn, err := cfg.Dial()
+	if err != nil {
+		fmt.Printf("unable to connect to AMQP server %s, %v\n", cfg.RedactedURL(), err)
+		return
+	}
+	defer conn.Close()
+	fmt.Printf("Connected to AMQP server: %s\n", cfg.RedactedURL())
+
+	// subscribe before reading the stored events, so the ones recorded in
+	// between aren't missed
+	sp := newSpectator(*gameID, gamelogic.NewWorld(*gameID))
+	sp.buffer()
+	err = subscribe(conn, sp)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	sp.catchUp(storedEvents(*gameID))
+
+	pages, _ := fs.Sub(static, "static")
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServerFS(pages))
+	mux.HandleFunc("GET /state", sp.handleState)
+	mux.HandleFunc("GET /events", sp.handleEvents)
+	srv := &http.Server{
+		Addr:              *httpAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	fmt.Printf("Watching game %s, the dashboard is on %s\n", *gameID, *httpAddr)
+	err = srv.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf("error serving the dashboard: %v\n", err)
+	}
+}
+
+// storedEvents loads the game's stored events when the spectator runs next
+// to the server, so it doesn't start from an empty map.
+func storedEvents(game string) []gamelogic.GameEvent {
+	events, err := gamelogic.LoadEvents(game, time.Time{})
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("error loading stored events: %v\n", err)
+		}
+		return nil
+	}
+	fmt.Printf("Caught up on %d stored events of %s\n", len(events), game)
+	return events
+}
+
+// subscribe listens in on the game on queues of its own. The world only
+// changes with the events the server has recorded, so spawns it turned down
+// never show up; the rest is for the feed.
+func subscribe(conn *amqp.Connection, sp *spectator) error {
+	game := sp.game
+	name := fmt.Sprintf("spectator_%d", os.Getpid())
+
+	err := pubsub.SubscribeJSON(
+		conn,
+		routing.ExchangePerilTopic,
+		routing.Key(routing.RecordedEventsPrefix, game, name),
+		routing.Key(routing.RecordedEventsPrefix, game),
+		pubsub.QueueTypeTransient,
+		handlerGameEvent(sp),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to recorded events queue: %w", err)
+	}
+
+	err = pubsub.SubscribeJSON(
+		conn,
+		routing.ExchangePerilDirect,
+		routing.Key(routing.PauseKey, game, name),
+		routing.Key(routing.PauseKey, game),
+		pubsub.QueueTypeTransient,
+		handlerPause(sp),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to pause queue: %w", err)
+	}
+
+	err = pubsub.SubscribeJSON(
+		conn,
+		routing.ExchangePerilTopic,
+		routing.Key(routing.PlayerJoinedPrefix, game, name),
+		routing.Key(routing.PlayerJoinedPrefix, game),
+		pubsub.QueueTypeTransient,
+		handlerPlayerJoined(sp),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to player joined queue: %w", err)
+	}
+
+	err = pubsub.SubscribeJSON(
+		conn,
+		routing.ExchangePerilTopic,
+		routing.Key(routing.PlayerLeftPrefix, game, name),
+		routing.Key(routing.PlayerLeftPrefix, game),
+		pubsub.QueueTypeTransient,
+		handlerPlayerLeft(sp),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to player left queue: %w", err)
+	}
+
+	err = pubsub.SubscribeJSON(
+		conn,
+		routing.ExchangePerilDirect,
+		routing.Key(routing.GameOverKey, game, name),
+		routing.Key(routing.GameOverKey, game),
+		pubsub.QueueTypeTransient,
+		handlerGameOver(sp),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to game over queue: %w", err)
+	}
+
+	err = pubsub.SubscribeJSON(
+		conn,
+		routing.ExchangePerilDirect,
+		routing.Key(routing.AdminPrefix, game, name),
+		routing.Key(routing.AdminPrefix, game),
+		pubsub.QueueTypeTransient,
+		handlerAdmin(sp),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to admin queue: %w", err)
+	}
+
+	err = pubsub.SubscribeGob(
+		conn,
+		routing.ExchangePerilTopic,
+		routing.Key(routing.GameLogSlug, game, name),
+		routing.Key(routing.GameLogSlug, game, "*"),
+		pubsub.QueueTypeTransient,
+		handlerGameLog(sp),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to game logs queue: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+// feedSize is how many feed items a new viewer gets to catch up on.
+const feedSize = 100
+
+// The kinds of feed items, which the page styles differently.
+const (
+	kindSpawn  = "spawn"
+	kindMove   = "move"
+	kindWar    = "war"
+	kindPause  = "pause"
+	kindPlayer = "player"
+	kindLog    = "log"
+	kindAdmin  = "admin"
+	kindGame   = "game"
+)
+
+// spectator follows a game and keeps every viewer of the dashboard up to
+// date. The world is rebuilt from the same events the server records.
+type spectator struct {
+	game  string
+	world *gamelogic.World
+
+	mu      sync.Mutex
+	feed    []feedItem
+	viewers map[chan message]struct{}
+	// pending holds the recorded events that arrive while catching up on
+	// the stored ones. It is nil once caught up.
+	pending []gamelogic.GameEvent
+}
+
+type feedItem struct {
+	Time time.Time `json:"time"`
+	Kind string    `json:"kind"`
+	Text string    `json:"text"`
+}
+
+// view is the state of the game as the page draws it.
+type view struct {
+	Game        string   `json:"game"`
+	Paused      bool     `json:"paused"`
+	Over        *over    `json:"over,omitempty"`
+	Territories []string `json:"territories"`
+	Players     []string `json:"players"`
+	// Units counts units by territory, then by player.
+	Units map[string]map[string]int `json:"units"`
+}
+
+type over struct {
+	Winner string `json:"winner"`
+	Reason string `json:"reason"`
+}
+
+// message is a server-sent event: "state" with a view or "feed" with a
+// feedItem.
+type message struct {
+	event string
+	data  []byte
+}
+
+func newSpectator(game string, world *gamelogic.World) *spectator {
+	return &spectator{
+		game:    game,
+		world:   world,
+		viewers: map[chan message]struct{}{},
+	}
+}
+
+func (sp *spectator) view() view {
+	v := view{
+		Game:        sp.game,
+		Paused:      sp.world.IsPaused(),
+		Territories: []string{},
+		Players:     sp.world.Usernames(),
+		Units:       map[string]map[string]int{},
+	}
+	for _, loc := range gamelogic.Locations() {
+		v.Territories = append(v.Territories, string(loc))
+		v.Units[string(loc)] = map[string]int{}
+	}
+	for _, name := range v.Players {
+		for _, unit := range sp.world.Player(name).GetPlayerSnap().Units {
+			v.Units[string(unit.Location)][name]++
+		}
+	}
+	if o := sp.world.GameOver(); o != nil {
+		v.Over = &over{Winner: o.Winner, Reason: o.Reason}
+	}
+	return v
+}
+
+// buffer holds on to the recorded events until catchUp, so the spectator
+// can subscribe before reading the stored ones and miss nothing in between.
+func (sp *spectator) buffer() {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.pending = []gamelogic.GameEvent{}
+}
+
+// catchUp applies the stored events, then the recorded ones that came in
+// meanwhile, and stops buffering.
+func (sp *spectator) catchUp(stored []gamelogic.GameEvent) {
+	for _, ev := range stored {
+		sp.world.Apply(ev)
+	}
+	for {
+		sp.mu.Lock()
+		pending := sp.pending
+		if len(pending) == 0 {
+			sp.pending = nil
+			sp.mu.Unlock()
+			break
+		}
+		sp.pending = []gamelogic.GameEvent{}
+		sp.mu.Unlock()
+		for _, ev := range pending {
+			sp.record(ev)
+		}
+	}
+	sp.pushState()
+}
+
+// receive takes a recorded event, holding on to it while catching up.
+func (sp *spectator) receive(ev gamelogic.GameEvent) {
+	sp.mu.Lock()
+	if sp.pending != nil {
+		sp.pending = append(sp.pending, ev)
+		sp.mu.Unlock()
+		return
+	}
+	sp.mu.Unlock()
+	sp.record(ev)
+}
+
+// record applies a recorded event the world hasn't seen yet.
+func (sp *spectator) record(ev gamelogic.GameEvent) {
+	if ev.Seq > 0 && ev.Seq <= sp.world.LastSeq() {
+		// already in the stored events
+		return
+	}
+	kind, text := describe(ev)
+	sp.apply(ev, kind, text)
+}
+
+// apply updates the world with ev and tells the viewers about it. Events
+// without a kind, like income, only change the state.
+func (sp *spectator) apply(ev gamelogic.GameEvent, kind, text string) {
+	sp.world.Apply(ev)
+	if kind != "" {
+		sp.report(kind, text)
+	}
+	sp.pushState()
+}
+
+// report adds an item to the feed.
+func (sp *spectator) report(kind, text string) {
+	item := feedItem{Time: time.Now(), Kind: kind, Text: text}
+	sp.mu.Lock()
+	sp.feed = append(sp.feed, item)
+	if len(sp.feed) > feedSize {
+		sp.feed = sp.feed[len(sp.feed)-feedSize:]
+	}
+	sp.mu.Unlock()
+	sp.broadcast("feed", item)
+}
+
+func (sp *spectator) pushState() {
+	sp.broadcast("state", sp.view())
+}
+
+// broadcast sends to every viewer. A viewer that can't keep up misses out,
+// the next state catches them up.
+func (sp *spectator) broadcast(event string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		fmt.Printf("error encoding %s: %v\n", event, err)
+		return
+	}
+	msg := message{event: event, data: data}
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	for ch := range sp.viewers {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+// watch adds a viewer. It gets the current state and the recent feed first.
+func (sp *spectator) watch() chan message {
+	ch := make(chan message, 64)
+	state, _ := json.Marshal(sp.view())
+	ch <- message{event: "state", data: state}
+
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	start := max(len(sp.feed)-(cap(ch)-1), 0)
+	for _, item := range sp.feed[start:] {
+		data, _ := json.Marshal(item)
+		ch <- message{event: "feed", data: data}
+	}
+	sp.viewers[ch] = struct{}{}
+	return ch
+}
+
+func (sp *spectator) unwatch(ch chan message) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	delete(sp.viewers, ch)
+}
+
+// describe puts a game event into words for the feed.
+func describe(ev gamelogic.GameEvent) (kind, text string) {
+	switch ev.Type {
+	case gamelogic.EventSpawn:
+		if ev.Unit != nil {
+			return kindSpawn, fmt.Sprintf("%s spawned %s in %s", ev.Username, ev.Unit.Rank, ev.Unit.Location)
+		}
+	case gamelogic.EventMove:
+		if ev.Move != nil {
+			return kindMove, fmt.Sprintf("%s moved %d unit(s) into %s", ev.Username, len(ev.Move.Units), ev.Move.ToLocation)
+		}
+	case gamelogic.EventWarDeclared:
+		if ev.War != nil {
+			return kindWar, fmt.Sprintf("%s and %s are at war", ev.War.Attacker.Username, ev.War.Defender.Username)
+		}
+	case gamelogic.EventWarOutcome:
+		if r := ev.Result; r != nil {
+			if r.Draw {
+				return kindWar, fmt.Sprintf("The war between %s and %s in %s was a draw", r.Winner, r.Loser, r.Location)
+			}
+			return kindWar, fmt.Sprintf("%s won a war against %s in %s", r.Winner, r.Loser, r.Location)
+		}
+	}
+	return "", ""
+}
+
+func describePause(ps routing.PlayingState) string {
+	if ps.IsPaused {
+		return "The game is paused"
+	}
+	return "The game is running again"
+}
+
+func describeGameOver(o routing.GameOver) string {
+	if o.Winner == "" {
+		return fmt.Sprintf("The game is over, it ended because %s", o.Reason)
+	}
+	return fmt.Sprintf("%s has won the game, it ended because %s", o.Winner, o.Reason)
+}
@@ -0,0 +1,240 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+func handlerPause(s *Session) func(routing.PlayingState) pubsub.AckType {
+	f := func(ps routing.PlayingState) pubsub.AckType {
+		defer fmt.Print("> ")
+		s.State.HandlePause(ps)
+		if ps.IsPaused {
+			s.event(EventPause)
+		} else {
+			s.event(EventResume)
+		}
+		return pubsub.Ack
+	}
+	return f
+}
+
+func handlerTurnStarted(s *Session) func(routing.TurnStarted) pubsub.AckType {
+	f := func(ts routing.TurnStarted) pubsub.AckType {
+		defer fmt.Print("> ")
+		s.State.HandleTurnStarted(ts)
+		s.event(EventTurn)
+		return pubsub.Ack
+	}
+	return f
+}
+
+// handlerTurnResolved takes the server's results for the turn. The server
+// carries out everyone's orders, so nothing is sent from here.
+func handlerTurnResolved(s *Session) func(gamelogic.TurnResolved) pubsub.AckType {
+	f := func(tr gamelogic.TurnResolved) pubsub.AckType {
+		defer fmt.Print("> ")
+		s.State.HandleTurnResolved(tr)
+		s.event(EventTurnResolved)
+		return pubsub.Ack
+	}
+	return f
+}
+
+func handlerPlayerJoined(s *Session) func(routing.PlayerJoined) pubsub.AckType {
+	f := func(pj routing.PlayerJoined) pubsub.AckType {
+		defer fmt.Print("> ")
+		s.State.HandlePlayerJoined(pj)
+		s.event(EventJoined)
+		return pubsub.Ack
+	}
+	return f
+}
+
+func handlerPlayerLeft(s *Session) func(routing.PlayerLeft) pubsub.AckType {
+	f := func(pl routing.PlayerLeft) pubsub.AckType {
+		defer fmt.Print("> ")
+		s.State.HandlePlayerLeft(pl)
+		s.event(EventLeft)
+		return pubsub.Ack
+	}
+	return f
+}
+
+func handlerAdmin(s *Session) func(routing.AdminMessage) pubsub.AckType {
+	f := func(msg routing.AdminMessage) pubsub.AckType {
+		outcome := s.State.HandleAdmin(msg)
+		if s.hooks.OnAdmin != nil {
+			s.hooks.OnAdmin(msg, outcome)
+		}
+		if outcome == gamelogic.AdminOutcomeKicked {
+			s.kickedOnce.Do(func() { close(s.kicked) })
+			s.event(EventKicked)
+			return pubsub.Ack
+		}
+		s.event(EventAdmin)
+		fmt.Print("> ")
+		return pubsub.Ack
+	}
+	return f
+}
+
+func handlerChat(s *Session) func(routing.ChatMessage) pubsub.AckType {
+	f := func(msg routing.ChatMessage) pubsub.AckType {
+		defer fmt.Print("> ")
+		s.State.HandleChat(msg)
+		if s.hooks.OnChat != nil {
+			s.hooks.OnChat(msg)
+		}
+		s.event(EventChat)
+		return pubsub.Ack
+	}
+	return f
+}
+
+func handlerDiplomacy(s *Session) func(routing.DiplomacyUpdate) pubsub.AckType {
+	f := func(du routing.DiplomacyUpdate) pubsub.AckType {
+		defer fmt.Print("> ")
+		s.State.HandleDiplomacy(du)
+		if s.hooks.OnDiplomacy != nil {
+			s.hooks.OnDiplomacy(du)
+		}
+		s.event(EventDiplomacy)
+		return pubsub.Ack
+	}
+	return f
+}
+
+func handlerGameOver(s *Session) func(routing.GameOver) pubsub.AckType {
+	f := func(over routing.GameOver) pubsub.AckType {
+		defer fmt.Print("> ")
+		s.State.HandleGameOver(over)
+		s.event(EventGameOver)
+		return pubsub.Ack
+	}
+	return f
+}
+
+func handlerEconomy(s *Session) func(routing.EconomyUpdate) pubsub.AckType {
+	f := func(eu routing.EconomyUpdate) pubsub.AckType {
+		s.State.HandleEconomyUpdate(eu)
+		s.event(EventEconomy)
+		if eu.RejectedUnitID != 0 {
+			fmt.Print("> ")
+		}
+		return pubsub.Ack
+	}
+	return f
+}
+
+func handlerMove(s *Session) func(gamelogic.ArmyMove) pubsub.AckType {
+	f := func(move gamelogic.ArmyMove) pubsub.AckType {
+		defer fmt.Print("> ")
+		outcome := s.State.HandleMove(move)
+		if outcome != gamelogic.MoveOutcomeSamePlayer {
+			if s.hooks.OnMove != nil {
+				s.hooks.OnMove(move, outcome)
+			}
+			s.event(EventMove)
+		}
+		switch outcome {
+		case gamelogic.MoveOutComeSafe:
+			return pubsub.Ack
+		case gamelogic.MoveOutcomeMakeWar:
+			if s.State.IsTurnBased() {
+				// the server fights the wars when it resolves the turn
+				return pubsub.Ack
+			}
+			msg := gamelogic.RecognitionOfWar{
+				Attacker: move.Player,
+				Defender: s.State.GetPlayerSnap(),
+			}.Contested()
+			exchange := routing.ExchangePerilTopic
+			key := routing.Key(routing.WarRecognitionsPrefix, s.Game, s.Username())
+			err := pubsub.PublishJSON(s.ch, exchange, key, msg)
+			if err != nil {
+				return pubsub.NackRequeue
+			}
+			err = s.pubGameEvent(gamelogic.NewWarDeclaredEvent(s.Game, s.Username(), msg))
+			if err != nil {
+				fmt.Printf("error publishing war event: %v\n", err)
+			}
+			return pubsub.Ack
+		case gamelogic.MoveOutcomeSamePlayer:
+			return pubsub.NackDiscard
+		default:
+			return pubsub.NackDiscard
+		}
+	}
+	return f
+}
+
+func handlerWar(s *Session) func(gamelogic.RecognitionOfWar) pubsub.AckType {
+	f := func(rw gamelogic.RecognitionOfWar) pubsub.AckType {
+		outcome, winner, loser := s.State.HandleWar(rw)
+		if outcome != gamelogic.WarOutcomeNotInvolved {
+			s.event(EventWar)
+		}
+		switch outcome {
+		case gamelogic.WarOutcomeNotInvolved:
+			// we're not involved, let another client pick this up
+			return pubsub.NackRequeue
+		case gamelogic.WarOutcomeNoUnits:
+			return pubsub.NackDiscard
+		case gamelogic.WarOutcomeDraw:
+			s.pubWarOutcome(rw, outcome, winner, loser)
+			msg := fmt.Sprintf("A war between %s and %s resulted in a draw", winner, loser)
+			err := s.Log(msg)
+			if err != nil {
+				return pubsub.NackRequeue
+			}
+			return pubsub.Ack
+		case gamelogic.WarOutcomeOpponentWon:
+			s.pubWarOutcome(rw, outcome, winner, loser)
+			msg := fmt.Sprintf("%s won a war against %s", winner, loser)
+			err := s.Log(msg)
+			if err != nil {
+				return pubsub.NackRequeue
+			}
+			return pubsub.Ack
+		case gamelogic.WarOutcomeYouWon:
+			s.pubWarOutcome(rw, outcome, winner, loser)
+			msg := fmt.Sprintf("%s won a war against %s", winner, loser)
+			err := s.Log(msg)
+			if err != nil {
+				return pubsub.NackRequeue
+			}
+			return pubsub.Ack
+		default:
+			fmt.Printf("error, unknown war outcome: %v\n", outcome)
+			return pubsub.NackDiscard
+		}
+	}
+	return f
+}
+
+func handlerWarResult(s *Session) func(gamelogic.GameEvent) pubsub.AckType {
+	f := func(ev gamelogic.GameEvent) pubsub.AckType {
+		defer fmt.Print("> ")
+		if ev.Result == nil {
+			return pubsub.NackDiscard
+		}
+		promoted := s.State.HandleWarResult(ev)
+		for _, unit := range promoted {
+			msg := fmt.Sprintf("%s's unit %v was promoted to %s", s.Username(), unit.ID, unit.Rank)
+			err := s.Log(msg)
+			if err != nil {
+				fmt.Printf("error logging promotion: %v\n", err)
+			}
+		}
+		if s.hooks.OnWarResult != nil {
+			s.hooks.OnWarResult(*ev.Result)
+		}
+		s.event(EventWarResult)
+		return pubsub.Ack
+	}
+	return f
+}
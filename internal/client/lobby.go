@@ -0,0 +1,114 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+const lobbyTimeout = 5 * time.Second
+
+// Lobby sends requests to the server's lobby and waits for the answers.
+type Lobby struct {
+	ch        *amqp.Channel
+	responses chan routing.LobbyResponse
+	username  string
+}
+
+// NewLobby gets the lobby's answers to username. The server would turn an
+// invalid username away, so it's refused here before anything is sent.
+func NewLobby(conn *amqp.Connection, username string) (*Lobby, error) {
+	err := routing.ValidateUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	channel, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("error creating channel: %w", err)
+	}
+	responses := make(chan routing.LobbyResponse, 1)
+	err = pubsub.SubscribeJSON(
+		conn,
+		routing.ExchangePerilDirect,
+		routing.Key(routing.LobbyKey, username),
+		routing.Key(routing.LobbyKey, username),
+		pubsub.QueueTypeTransient,
+		handlerLobby(responses),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error subscribing to JSON lobby queue: %w", err)
+	}
+	return &Lobby{
+		ch:        channel,
+		responses: responses,
+		username:  username,
+	}, nil
+}
+
+// Request sends an action to the server's lobby and waits for the answer.
+// fog is only used when creating a game.
+func (l *Lobby) Request(action, game string, fog bool) (routing.LobbyResponse, error) {
+	req := routing.LobbyRequest{
+		Username: l.username,
+		Action:   action,
+		Game:     game,
+		Fog:      fog,
+	}
+	err := pubsub.PublishJSON(l.ch, routing.ExchangePerilDirect, routing.LobbyKey, req)
+	if err != nil {
+		return routing.LobbyResponse{}, err
+	}
+
+	timeout := time.After(lobbyTimeout)
+	for {
+		select {
+		case resp := <-l.responses:
+			if resp.Action != action || resp.Game != game {
+				// a late answer to an earlier request
+				continue
+			}
+			if resp.Error != "" {
+				return resp, errors.New(resp.Error)
+			}
+			return resp, nil
+		case <-timeout:
+			return routing.LobbyResponse{}, errors.New("no answer from the server, is it running?")
+		}
+	}
+}
+
+// Enter creates or joins a game and returns what the server knows about it.
+func (l *Lobby) Enter(action, game string, fog bool) (routing.GameInfo, error) {
+	resp, err := l.Request(action, game, fog)
+	if err != nil {
+		return routing.GameInfo{}, err
+	}
+	return FindGame(resp.Games, game), nil
+}
+
+// FindGame picks id out of the games listed by the server.
+func FindGame(games []routing.GameInfo, id string) routing.GameInfo {
+	for _, g := range games {
+		if g.ID == id {
+			return g
+		}
+	}
+	return routing.GameInfo{ID: id}
+}
+
+func handlerLobby(responses chan<- routing.LobbyResponse) func(routing.LobbyResponse) pubsub.AckType {
+	f := func(resp routing.LobbyResponse) pubsub.AckType {
+		select {
+		case responses <- resp:
+		default:
+			// nobody is waiting for this answer
+		}
+		return pubsub.Ack
+	}
+	return f
+}
@@ -0,0 +1,507 @@
+// Package client is a player's side of a game: the subscriptions, presence
+// and orders shared by the interactive client and the bots.
+package client
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/config"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+// Hooks let a player react to what happens in the game. They are called
+// after the game state has been updated, from the subscription goroutines.
+type Hooks struct {
+	// OnMove is called for every move by another player.
+	OnMove func(move gamelogic.ArmyMove, outcome gamelogic.MoveOutcome)
+	// OnWarResult is called for every war fought in the game.
+	OnWarResult func(result gamelogic.WarResult)
+	// OnChat is called for every chat message the player gets.
+	OnChat func(msg routing.ChatMessage)
+	// OnDiplomacy is called for every treaty update the player hears about.
+	OnDiplomacy func(du routing.DiplomacyUpdate)
+	// OnAdmin is called for every admin message, including the one kicking
+	// the player.
+	OnAdmin func(msg routing.AdminMessage, outcome gamelogic.AdminOutcome)
+	// OnEvent is called with the name of everything the player hears about,
+	// one of the Event constants.
+	OnEvent func(event string)
+}
+
+// The events passed to Hooks.OnEvent.
+const (
+	EventMove         = "move"
+	EventArrival      = "arrival"
+	EventWar          = "war"
+	EventWarResult    = "war_result"
+	EventPause        = "pause"
+	EventResume       = "resume"
+	EventTurn         = "turn"
+	EventTurnResolved = "turn_resolved"
+	EventEconomy      = "economy"
+	EventGameOver     = "game_over"
+	EventJoined       = "joined"
+	EventLeft         = "left"
+	EventChat         = "chat"
+	EventDiplomacy    = "diplomacy"
+	EventAdmin        = "admin"
+	EventKicked       = "kicked"
+)
+
+// Events lists every event name, for validating scripts and the like.
+var Events = []string{
+	EventMove, EventArrival, EventWar, EventWarResult, EventPause, EventResume,
+	EventTurn, EventTurnResolved, EventEconomy, EventGameOver, EventJoined,
+	EventLeft, EventChat, EventDiplomacy, EventAdmin, EventKicked,
+}
+
+// Session is a player in a game. Everything for the game runs on its own
+// connection, so closing the session tears down all its subscriptions.
+type Session struct {
+	Game  string
+	State *gamelogic.GameState
+
+	conn       *amqp.Connection
+	ch         *amqp.Channel
+	hooks      Hooks
+	kicked     chan struct{}
+	kickedOnce sync.Once
+	stop       chan struct{}
+}
+
+// Join connects userName to a game and subscribes to everything the player
+// needs to hear about. In a fog of war game moves come filtered from the
+// server instead of straight from other clients.
+func Join(cfg config.Config, userName string, info routing.GameInfo, hooks Hooks) (*Session, error) {
+	conn, err := cfg.Dial()
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to AMQP server: %w", err)
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error creating channel: %w", err)
+	}
+
+	s := &Session{
+		Game:   info.ID,
+		State:  gamelogic.NewGameState(userName),
+		conn:   conn,
+		ch:     channel,
+		hooks:  hooks,
+		kicked: make(chan struct{}),
+		stop:   make(chan struct{}),
+	}
+	s.State.SetFogOfWar(info.Fog)
+	s.State.SeePlayers(info.Players...)
+
+	err = s.subscribe()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	err = s.pubPresence(routing.PresenceJoin)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go s.heartbeat()
+	go s.travel()
+	return s, nil
+}
+
+// Close tells the server the player has left and disconnects.
+func (s *Session) Close() {
+	close(s.stop)
+	err := s.pubPresence(routing.PresenceLeave)
+	if err != nil {
+		fmt.Printf("error leaving game %s: %v\n", s.Game, err)
+	}
+	s.conn.Close()
+}
+
+// Kicked is closed when an admin kicks the player from the game.
+func (s *Session) Kicked() <-chan struct{} {
+	return s.kicked
+}
+
+func (s *Session) Username() string {
+	return s.State.GetUsername()
+}
+
+func (s *Session) event(name string) {
+	if s.hooks.OnEvent != nil {
+		s.hooks.OnEvent(name)
+	}
+}
+
+func (s *Session) subscribe() error {
+	game := s.Game
+	userName := s.Username()
+
+	err := pubsub.SubscribeJSON(
+		s.conn,
+		routing.ExchangePerilDirect,
+		routing.Key(routing.PauseKey, game, userName),
+		routing.Key(routing.PauseKey, game),
+		pubsub.QueueTypeTransient,
+		handlerPause(s),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to JSON pause queue: %w", err)
+	}
+
+	movesExchange := routing.ExchangePerilTopic
+	movesKey := routing.Key(routing.ArmyMovesPrefix, game, "*")
+	if s.State.IsFogOfWar() {
+		movesExchange = routing.ExchangePerilDirect
+		movesKey = routing.Key(routing.ArmyMovesPrefix, game, userName)
+	}
+	err = pubsub.SubscribeJSON(
+		s.conn,
+		movesExchange,
+		routing.Key(routing.ArmyMovesPrefix, game, userName),
+		movesKey,
+		pubsub.QueueTypeTransient,
+		handlerMove(s),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to JSON moves queue: %w", err)
+	}
+
+	err = pubsub.SubscribeJSON(
+		s.conn,
+		routing.ExchangePerilTopic,
+		routing.Key(routing.WarRecognitionsPrefix, game),
+		routing.Key(routing.WarRecognitionsPrefix, game, "*"),
+		pubsub.QueueTypeDurable,
+		handlerWar(s),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to JSON war queue: %w", err)
+	}
+
+	err = pubsub.SubscribeJSON(
+		s.conn,
+		routing.ExchangePerilDirect,
+		routing.Key(routing.WarResultsPrefix, game, userName),
+		routing.Key(routing.WarResultsPrefix, game),
+		pubsub.QueueTypeTransient,
+		handlerWarResult(s),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to JSON war results queue: %w", err)
+	}
+
+	err = pubsub.SubscribeJSON(
+		s.conn,
+		routing.ExchangePerilDirect,
+		routing.Key(routing.TurnKey, game, userName),
+		routing.Key(routing.TurnKey, game),
+		pubsub.QueueTypeTransient,
+		handlerTurnStarted(s),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to JSON turn queue: %w", err)
+	}
+
+	err = pubsub.SubscribeJSON(
+		s.conn,
+		routing.ExchangePerilDirect,
+		routing.Key(routing.TurnResolvedKey, game, userName),
+		routing.Key(routing.TurnResolvedKey, game, userName),
+		pubsub.QueueTypeTransient,
+		handlerTurnResolved(s),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to JSON turn resolution queue: %w", err)
+	}
+
+	err = pubsub.SubscribeJSON(
+		s.conn,
+		routing.ExchangePerilDirect,
+		routing.Key(routing.EconomyPrefix, game, userName),
+		routing.Key(routing.EconomyPrefix, game, userName),
+		pubsub.QueueTypeTransient,
+		handlerEconomy(s),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to JSON economy queue: %w", err)
+	}
+
+	err = pubsub.SubscribeJSON(
+		s.conn,
+		routing.ExchangePerilDirect,
+		routing.Key(routing.GameOverKey, game, userName),
+		routing.Key(routing.GameOverKey, game),
+		pubsub.QueueTypeTransient,
+		handlerGameOver(s),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to JSON game over queue: %w", err)
+	}
+
+	err = pubsub.SubscribeJSON(
+		s.conn,
+		routing.ExchangePerilTopic,
+		routing.Key(routing.PlayerJoinedPrefix, game, userName),
+		routing.Key(routing.PlayerJoinedPrefix, game),
+		pubsub.QueueTypeTransient,
+		handlerPlayerJoined(s),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to JSON player joined queue: %w", err)
+	}
+
+	err = pubsub.SubscribeJSON(
+		s.conn,
+		routing.ExchangePerilTopic,
+		routing.Key(routing.PlayerLeftPrefix, game, userName),
+		routing.Key(routing.PlayerLeftPrefix, game),
+		pubsub.QueueTypeTransient,
+		handlerPlayerLeft(s),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to JSON player left queue: %w", err)
+	}
+
+	err = pubsub.SubscribeJSON(
+		s.conn,
+		routing.ExchangePerilDirect,
+		routing.Key(routing.AdminPrefix, game, userName),
+		routing.Key(routing.AdminPrefix, game),
+		pubsub.QueueTypeTransient,
+		handlerAdmin(s),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to JSON admin queue: %w", err)
+	}
+
+	err = pubsub.SubscribeJSON(
+		s.conn,
+		routing.ExchangePerilTopic,
+		routing.Key(routing.ChatPrefix, game, userName, "all"),
+		routing.Key(routing.ChatPrefix, game),
+		pubsub.QueueTypeTransient,
+		handlerChat(s),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to JSON chat queue: %w", err)
+	}
+
+	err = pubsub.SubscribeJSON(
+		s.conn,
+		routing.ExchangePerilTopic,
+		routing.Key(routing.ChatPrefix, game, userName),
+		routing.Key(routing.ChatPrefix, game, userName),
+		pubsub.QueueTypeTransient,
+		handlerChat(s),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to JSON whisper queue: %w", err)
+	}
+
+	err = pubsub.SubscribeJSON(
+		s.conn,
+		routing.ExchangePerilDirect,
+		routing.Key(routing.DiplomacyPrefix, game, userName),
+		routing.Key(routing.DiplomacyPrefix, game, userName),
+		pubsub.QueueTypeTransient,
+		handlerDiplomacy(s),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to JSON diplomacy queue: %w", err)
+	}
+
+	err = pubsub.SubscribeJSON(
+		s.conn,
+		routing.ExchangePerilDirect,
+		routing.Key(routing.DiplomacyPrefix, game, userName, "public"),
+		routing.Key(routing.DiplomacyPrefix, game),
+		pubsub.QueueTypeTransient,
+		handlerDiplomacy(s),
+	)
+	if err != nil {
+		return fmt.Errorf("error subscribing to JSON public diplomacy queue: %w", err)
+	}
+	return nil
+}
+
+// Order carries out a spawn or move right away and announces it.
+func (s *Session) Order(words []string) error {
+	gs := s.State
+	switch words[0] {
+	case "spawn":
+		unit, err := gs.CommandSpawn(words)
+		if err != nil {
+			return err
+		}
+		err = s.pubGameEvent(gamelogic.NewSpawnEvent(s.Game, gs.GetUsername(), unit))
+		if err != nil {
+			return fmt.Errorf("error publishing spawn event: %w", err)
+		}
+	case "move":
+		move, err := gs.CommandMove(words)
+		if err != nil {
+			return err
+		}
+		err = s.pubMove(move)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown order: %s", words[0])
+	}
+	return nil
+}
+
+// Submit sends the orders queued for the current turn to the server.
+func (s *Session) Submit() error {
+	orders, err := s.State.CommandSubmit()
+	if err != nil {
+		return err
+	}
+	orders.Game = s.Game
+	key := routing.Key(routing.TurnOrdersKey, s.Game, s.Username())
+	err = pubsub.PublishJSON(s.ch, routing.ExchangePerilTopic, key, orders)
+	if err != nil {
+		return fmt.Errorf("error publishing orders: %w", err)
+	}
+	return nil
+}
+
+func (s *Session) Diplomacy(req routing.DiplomacyRequest) error {
+	req.Game = s.Game
+	key := routing.Key(routing.DiplomacyPrefix, s.Game, s.Username())
+	err := pubsub.PublishJSON(s.ch, routing.ExchangePerilTopic, key, req)
+	if err != nil {
+		return fmt.Errorf("error publishing diplomacy request: %w", err)
+	}
+	return nil
+}
+
+func (s *Session) Chat(msg routing.ChatMessage) error {
+	msg.Game = s.Game
+	key := routing.Key(routing.ChatPrefix, s.Game)
+	if msg.To != "" {
+		key = routing.Key(routing.ChatPrefix, s.Game, msg.To)
+	}
+	return pubsub.PublishJSON(s.ch, routing.ExchangePerilTopic, key, msg)
+}
+
+func (s *Session) Log(msg string) error {
+	exchange := routing.ExchangePerilTopic
+	key := routing.Key(routing.GameLogSlug, s.Game, s.Username())
+	gl := routing.GameLog{
+		CurrentTime: time.Now(),
+		Message:     msg,
+		Username:    s.Username(),
+		Game:        s.Game,
+	}
+	return pubsub.PublishGob(s.ch, exchange, key, gl)
+}
+
+func (s *Session) pubGameEvent(ev gamelogic.GameEvent) error {
+	exchange := routing.ExchangePerilTopic
+	key := routing.Key(routing.GameEventsPrefix, ev.Game, ev.Username)
+	return pubsub.PublishJSON(s.ch, exchange, key, ev)
+}
+
+// pubMove tells the other players about a departure or an arrival. In fog of
+// war the server sends out arrivals from the move event instead, and
+// departures stay hidden.
+func (s *Session) pubMove(move gamelogic.ArmyMove) error {
+	if !s.State.IsFogOfWar() {
+		key := routing.Key(routing.ArmyMovesPrefix, s.Game, s.Username())
+		err := pubsub.PublishJSON(s.ch, routing.ExchangePerilTopic, key, move)
+		if err != nil {
+			return fmt.Errorf("error publishing move: %w", err)
+		}
+		fmt.Printf("Successfully published move: %s %s\n", move.Player.Username, move.ToLocation)
+	}
+	if !move.Arrival {
+		return nil
+	}
+	err := s.pubGameEvent(gamelogic.NewMoveEvent(s.Game, move))
+	if err != nil {
+		return fmt.Errorf("error publishing move event: %w", err)
+	}
+	return nil
+}
+
+func (s *Session) pubWarOutcome(rw gamelogic.RecognitionOfWar, outcome gamelogic.WarOutcome, winner, loser string) {
+	ev := gamelogic.NewWarOutcomeEvent(s.Game, s.Username(), rw, outcome, winner, loser)
+	err := s.pubGameEvent(ev)
+	if err != nil {
+		fmt.Printf("error publishing war outcome event: %v\n", err)
+	}
+}
+
+func (s *Session) pubPresence(status string) error {
+	exchange := routing.ExchangePerilTopic
+	key := routing.Key(routing.PresencePrefix, s.Game, s.Username())
+	pr := routing.Presence{
+		Game:     s.Game,
+		Username: s.Username(),
+		Status:   status,
+		Time:     time.Now(),
+	}
+	err := pubsub.PublishJSON(s.ch, exchange, key, pr)
+	if err != nil {
+		return fmt.Errorf("error publishing presence: %w", err)
+	}
+	return nil
+}
+
+// heartbeat tells the server we're still here until the session is closed
+// or the player is kicked.
+func (s *Session) heartbeat() {
+	ticker := time.NewTicker(routing.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err := s.pubPresence(routing.PresenceHeartbeat)
+			if err != nil {
+				fmt.Printf("error sending heartbeat: %v\n", err)
+			}
+		case <-s.kicked:
+			return
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// travel moves units in transit along and announces them as they land,
+// until the session is closed or the player is kicked.
+func (s *Session) travel() {
+	ticker := time.NewTicker(gamelogic.TransitTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, move := range s.State.AdvanceTransit(gamelogic.TransitTick) {
+				fmt.Printf("\n%v unit(s) arrived in %s\n", len(move.Units), move.ToLocation)
+				err := s.pubMove(move)
+				if err != nil {
+					fmt.Printf("error announcing arrival: %v\n", err)
+				}
+				s.event(EventArrival)
+				fmt.Print("> ")
+			}
+		case <-s.kicked:
+			return
+		case <-s.stop:
+			return
+		}
+	}
+}
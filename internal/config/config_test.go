@@ -0,0 +1,176 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+var configFiles = map[string]string{
+	"peril.toml": `
+exchange_direct = "file_direct"
+exchange_topic = "file_topic"
+prefetch = 3
+log_write_delay = "2s"
+
+[tls]
+min_version = "1.3"
+`,
+	"peril.yaml": `
+exchange_direct: file_direct
+exchange_topic: file_topic
+prefetch: 3
+log_write_delay: 2s
+tls:
+  min_version: "1.3"
+`,
+}
+
+// clearEnv unsets every PERIL_* variable for the test, so only what the
+// test sets is seen.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	names := []string{"PERIL_CONFIG"}
+	for _, s := range settings {
+		names = append(names, envName(s.name))
+	}
+	for _, name := range names {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+}
+
+// keepGlobals puts back the package settings Load applies.
+func keepGlobals(t *testing.T) {
+	direct, topic, dlx, dlq := routing.ExchangePerilDirect, routing.ExchangePerilTopic, routing.ExchangePerilDLX, routing.DeadLetterQueue
+	prefetch, logFile, delay := pubsub.Prefetch, gamelogic.LogsFile, gamelogic.WriteToDiskSleep
+	t.Cleanup(func() {
+		routing.ExchangePerilDirect, routing.ExchangePerilTopic, routing.ExchangePerilDLX, routing.DeadLetterQueue = direct, topic, dlx, dlq
+		pubsub.Prefetch, gamelogic.LogsFile, gamelogic.WriteToDiskSleep = prefetch, logFile, delay
+	})
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	for name, contents := range configFiles {
+		t.Run(name, func(t *testing.T) {
+			clearEnv(t)
+			keepGlobals(t)
+			path := filepath.Join(t.TempDir(), name)
+			writeFile(t, path, []byte(contents))
+
+			// exchange-direct is only in the file, exchange-topic is in the
+			// file and the environment, and prefetch is everywhere.
+			t.Setenv("PERIL_CONFIG", path)
+			t.Setenv("PERIL_EXCHANGE_TOPIC", "env_topic")
+			t.Setenv("PERIL_PREFETCH", "5")
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			f := RegisterFlags(fs)
+			err := fs.Parse([]string{"-prefetch", "7"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := Load(f)
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			want := Default()
+			want.ExchangeDirect = "file_direct"
+			want.ExchangeTopic = "env_topic"
+			want.Prefetch = 7
+			want.LogWriteDelay = 2 * time.Second
+			want.TLS.MinVersion = "1.3"
+			if c != want {
+				t.Errorf("Load() = %+v, want %+v", c, want)
+			}
+			if routing.ExchangePerilTopic != "env_topic" || pubsub.Prefetch != 7 {
+				t.Errorf("Load() didn't apply the config: topic %s, prefetch %d", routing.ExchangePerilTopic, pubsub.Prefetch)
+			}
+		})
+	}
+}
+
+func TestLoadFlagBeatsEnv(t *testing.T) {
+	clearEnv(t)
+	keepGlobals(t)
+	t.Setenv("PERIL_LOG_FILE", "env.log")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	f := RegisterFlags(fs)
+	err := fs.Parse([]string{"-log-file", "flag.log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(f)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if c.LogFile != "flag.log" {
+		t.Errorf("LogFile = %s, want the flag's flag.log", c.LogFile)
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	clearEnv(t)
+	keepGlobals(t)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	f := RegisterFlags(fs)
+	c, err := Load(f)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if c != Default() {
+		t.Errorf("Load() = %+v, want the defaults", c)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		env   map[string]string
+		flags []string
+	}{
+		{name: "bad env number", env: map[string]string{"PERIL_PREFETCH": "lots"}},
+		{name: "bad file", file: "peril.toml"},
+		{name: "unknown file type", file: "peril.ini"},
+		{name: "same exchange twice", flags: []string{"-exchange-topic", "peril_direct"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			keepGlobals(t)
+			if tt.file != "" {
+				path := filepath.Join(t.TempDir(), tt.file)
+				writeFile(t, path, []byte("prefetch = = 3"))
+				t.Setenv("PERIL_CONFIG", path)
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			f := RegisterFlags(fs)
+			err := fs.Parse(tt.flags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = Load(f)
+			if err == nil {
+				t.Error("Load() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	err := os.WriteFile(path, data, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// TLS is how to reach a broker that only takes amqps:// connections,
+// possibly with client certificates.
+type TLS struct {
+	CAFile       string `toml:"ca_file" yaml:"ca_file"`
+	CertFile     string `toml:"cert_file" yaml:"cert_file"`
+	KeyFile      string `toml:"key_file" yaml:"key_file"`
+	ServerName   string `toml:"server_name" yaml:"server_name"`
+	MinVersion   string `toml:"min_version" yaml:"min_version"`
+	ExternalAuth bool   `toml:"external_auth" yaml:"external_auth"`
+}
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func (c Config) usesTLS() bool {
+	uri, err := amqp.ParseURI(c.AMQPURL)
+	return err == nil && uri.Scheme == "amqps"
+}
+
+func (c Config) validateTLS() []error {
+	errs := []error{}
+	t := c.TLS
+	if _, ok := tlsVersions[t.MinVersion]; !ok {
+		errs = append(errs, fmt.Errorf("tls-min-version should be 1.2 or 1.3, not %q", t.MinVersion))
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		errs = append(errs, errors.New("tls-cert-file and tls-key-file must be set together"))
+	}
+	if t.ExternalAuth && t.CertFile == "" {
+		errs = append(errs, errors.New("auth-external needs a client certificate in tls-cert-file"))
+	}
+	tlsSet := t.CAFile != "" || t.CertFile != "" || t.ServerName != "" || t.ExternalAuth
+	if tlsSet && !c.usesTLS() {
+		errs = append(errs, errors.New("the tls settings need an amqps:// amqp-url"))
+	}
+	return errs
+}
+
+// TLSConfig builds the TLS client config for the broker connection.
+func (c Config) TLSConfig() (*tls.Config, error) {
+	t := c.TLS
+	tc := &tls.Config{
+		ServerName: t.ServerName,
+		MinVersion: tlsVersions[t.MinVersion],
+	}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", t.CAFile)
+		}
+		tc.RootCAs = pool
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %v", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	return tc, nil
+}
+
+// Dial connects to the broker, over TLS for amqps:// URLs. Every binary
+// connects through here.
+func (c Config) Dial() (*amqp.Connection, error) {
+	if !c.usesTLS() {
+		return amqp.Dial(c.AMQPURL)
+	}
+	tc, err := c.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	ac := amqp.Config{
+		Heartbeat:       10 * time.Second,
+		Locale:          "en_US",
+		TLSClientConfig: tc,
+	}
+	if c.TLS.ExternalAuth {
+		ac.SASL = []amqp.Authentication{&amqp.ExternalAuth{}}
+	}
+	return amqp.DialConfig(c.AMQPURL, ac)
+}
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const brokerName = "peril-broker"
+
+// testPKI is a CA with a server and a client certificate signed by it.
+type testPKI struct {
+	caPool *x509.CertPool
+	server tls.Certificate
+	// the files the client side is configured with
+	caFile, certFile, keyFile string
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	dir := t.TempDir()
+
+	caKey := newKey(t)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Peril test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	issue := func(serial int64, name string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+		key := newKey(t)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			DNSNames:     []string{name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+		return certPEM, keyPEM
+	}
+
+	serverCert, serverKey := issue(2, brokerName, x509.ExtKeyUsageServerAuth)
+	server, err := tls.X509KeyPair(serverCert, serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCert, clientKey := issue(3, "peril-client", x509.ExtKeyUsageClientAuth)
+
+	p := &testPKI{
+		caPool:   pool,
+		server:   server,
+		caFile:   filepath.Join(dir, "ca.pem"),
+		certFile: filepath.Join(dir, "client.pem"),
+		keyFile:  filepath.Join(dir, "client-key.pem"),
+	}
+	writeFile(t, p.caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}))
+	writeFile(t, p.certFile, clientCert)
+	writeFile(t, p.keyFile, clientKey)
+	return p
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// startBroker starts a stand-in for a TLS-terminating broker that requires
+// client certificates signed by the test CA. It greets every client that
+// gets through the handshake.
+func startBroker(t *testing.T, p *testPKI, maxVersion uint16) string {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{p.server},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    p.caPool,
+		MaxVersion:   maxVersion,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if conn.(*tls.Conn).Handshake() == nil {
+					io.WriteString(conn, "AMQP")
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// connect dials the stand-in with the client side of c and reads its
+// greeting. With TLS 1.3 a rejected client certificate only shows up on the
+// first read.
+func connect(c Config, addr string) (*tls.ConnectionState, error) {
+	tc, err := c.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}, "tcp", addr, tc)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	greeting := make([]byte, 4)
+	_, err = io.ReadFull(conn, greeting)
+	if err != nil {
+		return nil, err
+	}
+	state := conn.ConnectionState()
+	return &state, nil
+}
+
+func tlsConfig(p *testPKI) Config {
+	c := Default()
+	c.AMQPURL = "amqps://localhost:5671/"
+	c.TLS = TLS{
+		CAFile:     p.caFile,
+		CertFile:   p.certFile,
+		KeyFile:    p.keyFile,
+		ServerName: brokerName,
+		MinVersion: "1.2",
+	}
+	return c
+}
+
+func TestTLSHandshake(t *testing.T) {
+	p := newTestPKI(t)
+	addr := startBroker(t, p, 0)
+
+	c := tlsConfig(p)
+	errs := c.validateTLS()
+	if len(errs) != 0 {
+		t.Fatalf("validateTLS() = %v", errs)
+	}
+	state, err := connect(c, addr)
+	if err != nil {
+		t.Fatalf("handshake with the configured CA, cert and key failed: %v", err)
+	}
+	if len(state.PeerCertificates) == 0 || state.PeerCertificates[0].Subject.CommonName != brokerName {
+		t.Errorf("connected to the wrong broker: %+v", state.PeerCertificates)
+	}
+}
+
+func TestTLSWithoutClientCert(t *testing.T) {
+	p := newTestPKI(t)
+	addr := startBroker(t, p, 0)
+
+	c := tlsConfig(p)
+	c.TLS.CertFile = ""
+	c.TLS.KeyFile = ""
+	_, err := connect(c, addr)
+	if err == nil {
+		t.Fatal("the broker let in a client without a certificate")
+	}
+}
+
+func TestTLSMinVersion(t *testing.T) {
+	p := newTestPKI(t)
+	addr := startBroker(t, p, tls.VersionTLS12)
+
+	c := tlsConfig(p)
+	state, err := connect(c, addr)
+	if err != nil {
+		t.Fatalf("TLS 1.2 with min version 1.2 failed: %v", err)
+	}
+	if state.Version != tls.VersionTLS12 {
+		t.Errorf("negotiated version %x, want TLS 1.2", state.Version)
+	}
+
+	c.TLS.MinVersion = "1.3"
+	_, err = connect(c, addr)
+	if err == nil {
+		t.Fatal("connected to a TLS 1.2 broker with min version 1.3")
+	}
+	if !strings.Contains(err.Error(), "version") {
+		t.Errorf("error = %v, want a protocol version error", err)
+	}
+}
+
+func TestTLSServerName(t *testing.T) {
+	p := newTestPKI(t)
+	addr := startBroker(t, p, 0)
+
+	c := tlsConfig(p)
+	c.TLS.ServerName = "someone-else"
+	_, err := connect(c, addr)
+	if err == nil {
+		t.Fatal("accepted a broker certificate for another name")
+	}
+	var hostErr x509.HostnameError
+	if !errors.As(err, &hostErr) {
+		t.Errorf("error = %v, want a hostname mismatch", err)
+	}
+}
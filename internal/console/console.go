@@ -0,0 +1,194 @@
+// Package console is the line editor behind the client and server REPLs. It
+// keeps a history file, has the usual Emacs key bindings and completes
+// commands and their arguments on tab. Anything printed while a line is
+// being typed goes above the prompt, which is then drawn again.
+package console
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chzyer/readline"
+)
+
+const prompt = "> "
+
+// ErrNotTerminal is returned by Start when stdin isn't a terminal, e.g. when
+// commands are piped in. The plain reader works fine for that.
+var ErrNotTerminal = errors.New("stdin is not a terminal")
+
+// Completer returns the words that can go where the last of words is. The
+// last word is empty when the cursor comes right after a space.
+type Completer func(words []string) []string
+
+type Console struct {
+	rl     *readline.Instance
+	stdout *os.File
+	pipe   *os.File
+	// output is closed once everything printed has been written out.
+	output chan struct{}
+}
+
+// HistoryFile is where a program called name keeps its history by default,
+// in the home directory. It's empty if there is no home directory.
+func HistoryFile(name string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, "."+name+"_history")
+}
+
+// Start puts the line editor on the terminal. The history is kept in
+// historyFile, or nowhere if it's empty.
+func Start(historyFile string, complete Completer) (*Console, error) {
+	if !readline.DefaultIsTerminal() {
+		return nil, ErrNotTerminal
+	}
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:            prompt,
+		HistoryFile:       historyFile,
+		HistorySearchFold: true,
+		AutoComplete:      completer(complete),
+		InterruptPrompt:   "^C",
+		EOFPrompt:         "quit",
+		Stdout:            os.Stdout,
+		Stderr:            os.Stderr,
+	})
+	if err != nil {
+		return nil, err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		rl.Close()
+		return nil, err
+	}
+
+	c := &Console{
+		rl:     rl,
+		stdout: os.Stdout,
+		pipe:   w,
+		output: make(chan struct{}),
+	}
+	os.Stdout = w
+	go c.forward(r)
+	return c, nil
+}
+
+// ReadLine reads the next line. Ctrl-C throws away the line being typed and
+// Ctrl-D on an empty line reads as quit.
+func (c *Console) ReadLine() (string, bool) {
+	line, err := c.rl.Readline()
+	switch {
+	case errors.Is(err, readline.ErrInterrupt):
+		return "", true
+	case errors.Is(err, io.EOF):
+		return "quit", true
+	case err != nil:
+		return "", false
+	}
+	return line, true
+}
+
+// Close takes the line editor off the terminal and gives stdout back.
+func (c *Console) Close() {
+	os.Stdout = c.stdout
+	c.pipe.Close()
+	<-c.output
+	c.rl.Close()
+}
+
+// idle is how long forward waits for the rest of a line before writing out
+// what it has, so a question printed without a newline isn't held back.
+const idle = 100 * time.Millisecond
+
+// forward writes what the program prints above the prompt, one line at a
+// time. The "> " the handlers print to bring the prompt back are dropped,
+// since the line editor does that itself. A partial line is written out
+// once nothing more has been printed for a moment.
+func (c *Console) forward(r io.Reader) {
+	defer close(c.output)
+	out := c.rl.Stdout()
+	chunks := make(chan []byte)
+	go func() {
+		defer close(chunks)
+		for {
+			buf := make([]byte, 4096)
+			n, err := r.Read(buf)
+			if n > 0 {
+				chunks <- buf[:n]
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var pending []byte
+	timer := time.NewTimer(idle)
+	timer.Stop()
+	for {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				writeLine(out, string(pending))
+				return
+			}
+			pending = append(pending, chunk...)
+			for {
+				i := bytes.IndexByte(pending, '\n')
+				if i < 0 {
+					break
+				}
+				writeLine(out, string(pending[:i]))
+				pending = pending[i+1:]
+			}
+			timer.Stop()
+			if len(pending) > 0 {
+				timer.Reset(idle)
+			}
+		case <-timer.C:
+			writeLine(out, string(pending))
+			pending = nil
+		}
+	}
+}
+
+// writeLine writes line above the prompt, without any "> " in front of it.
+// A line that was only a prompt isn't written at all.
+func writeLine(out io.Writer, line string) {
+	prompted := strings.HasPrefix(line, prompt)
+	for strings.HasPrefix(line, prompt) {
+		line = strings.TrimPrefix(line, prompt)
+	}
+	if line == "" || prompted && strings.TrimSpace(line) == "" {
+		return
+	}
+	io.WriteString(out, line+"\n")
+}
+
+// completer adapts a Completer to readline, which wants the rest of each
+// candidate after what's been typed of it.
+type completer Completer
+
+func (f completer) Do(line []rune, pos int) ([][]rune, int) {
+	before := string(line[:pos])
+	words := strings.Fields(before)
+	if len(words) == 0 || strings.HasSuffix(before, " ") {
+		words = append(words, "")
+	}
+	word := words[len(words)-1]
+
+	var rest [][]rune
+	for _, c := range f(words) {
+		if strings.HasPrefix(c, word) {
+			rest = append(rest, []rune(strings.TrimPrefix(c, word)+" "))
+		}
+	}
+	return rest, len([]rune(word))
+}
@@ -0,0 +1,56 @@
+package gamelogic
+
+import (
+	"fmt"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+type AdminOutcome int
+
+const (
+	AdminOutcomeNone AdminOutcome = iota
+	AdminOutcomeKicked
+	AdminOutcomeReset
+)
+
+func (gs *GameState) HandleAdmin(msg routing.AdminMessage) AdminOutcome {
+	defer fmt.Println("************************")
+	fmt.Println()
+	switch msg.Kind {
+	case routing.AdminBroadcast:
+		fmt.Println("**** ADMIN BROADCAST ****")
+		fmt.Println(msg.Message)
+	case routing.AdminKick:
+		fmt.Println("**** ADMIN: PLAYER KICKED ****")
+		if msg.Target != gs.GetUsername() {
+			fmt.Printf("%s has been kicked from %s. Their units have been removed.\n", msg.Target, msg.Game)
+			return AdminOutcomeNone
+		}
+		fmt.Printf("You have been kicked from %s and your units have been forfeited.\n", msg.Game)
+		fmt.Println("Press enter to return to the lobby.")
+		return AdminOutcomeKicked
+	case routing.AdminReset:
+		fmt.Println("**** ADMIN: GAME RESET ****")
+		gs.reset()
+		fmt.Printf("%s has been reset. All units are gone and balances are back to %d.\n", msg.Game, StartingBalance)
+		return AdminOutcomeReset
+	default:
+		fmt.Printf("**** ADMIN: unknown message %s ****\n", msg.Kind)
+	}
+	return AdminOutcomeNone
+}
+
+func (gs *GameState) reset() {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.Player.Units = map[int]Unit{}
+	gs.balance = StartingBalance
+	gs.gameOver = nil
+	gs.turn.orders = nil
+	gs.turn.submitted = false
+}
+
+func NewResetEvent(game string) GameEvent {
+	return newEvent(EventReset, game, "")
+}
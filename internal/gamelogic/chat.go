@@ -0,0 +1,67 @@
+package gamelogic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+// clearLine moves back to the start of the prompt line and erases it, so
+// chat doesn't end up in the middle of whatever is being typed.
+const clearLine = "\r\033[K"
+
+func (gs *GameState) CommandSay(words []string) (routing.ChatMessage, error) {
+	if len(words) < 2 {
+		return routing.ChatMessage{}, errors.New("usage: say <message>")
+	}
+	return routing.ChatMessage{
+		From:    gs.GetUsername(),
+		Message: strings.Join(words[1:], " "),
+		Time:    time.Now(),
+	}, nil
+}
+
+func (gs *GameState) CommandWhisper(words []string) (routing.ChatMessage, error) {
+	if len(words) < 3 {
+		return routing.ChatMessage{}, errors.New("usage: whisper <user> <message>")
+	}
+	if words[1] == gs.GetUsername() {
+		return routing.ChatMessage{}, errors.New("you can't whisper to yourself")
+	}
+	msg := routing.ChatMessage{
+		From:    gs.GetUsername(),
+		To:      words[1],
+		Message: strings.Join(words[2:], " "),
+		Time:    time.Now(),
+	}
+	fmt.Printf("You whisper to %s: %s\n", msg.To, msg.Message)
+	return msg, nil
+}
+
+func (gs *GameState) HandleChat(msg routing.ChatMessage) {
+	fmt.Print(clearLine)
+	stamp := msg.Time.Format(time.TimeOnly)
+	if msg.To != "" {
+		fmt.Printf("[%s] %s whispers: %s\n", stamp, msg.From, msg.Message)
+		return
+	}
+	fmt.Printf("[%s] %s: %s\n", stamp, msg.From, msg.Message)
+}
+
+// ChatLog turns a chat message into a game log line so it's stored with the
+// rest of the game's logs.
+func ChatLog(msg routing.ChatMessage) routing.GameLog {
+	text := "(chat) " + msg.Message
+	if msg.To != "" {
+		text = fmt.Sprintf("(whisper to %s) %s", msg.To, msg.Message)
+	}
+	return routing.GameLog{
+		CurrentTime: msg.Time,
+		Message:     text,
+		Username:    msg.From,
+		Game:        msg.Game,
+	}
+}
@@ -0,0 +1,346 @@
+package gamelogic
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+func treatyActive(t routing.Treaty, now time.Time) bool {
+	return t.Expires.IsZero() || now.Before(t.Expires)
+}
+
+func treatyOther(t routing.Treaty, username string) string {
+	if t.Proposer == username {
+		return t.Partner
+	}
+	return t.Proposer
+}
+
+func describeTreaty(t routing.Treaty) string {
+	if t.Kind == routing.TreatyCeasefire && !t.Expires.IsZero() {
+		return fmt.Sprintf("ceasefire between %s and %s until %s", t.Proposer, t.Partner, t.Expires.Format(time.TimeOnly))
+	}
+	return fmt.Sprintf("%s between %s and %s", t.Kind, t.Proposer, t.Partner)
+}
+
+// Diplomacy is the server's record of treaties in a game. Both sides see the
+// same treaties because only the server changes them.
+type Diplomacy struct {
+	mu        sync.Mutex
+	proposals map[string]routing.Treaty
+	treaties  map[string]routing.Treaty
+}
+
+func NewDiplomacy() *Diplomacy {
+	return &Diplomacy{
+		proposals: map[string]routing.Treaty{},
+		treaties:  map[string]routing.Treaty{},
+	}
+}
+
+func pairKey(a, b string) string {
+	if a > b {
+		a, b = b, a
+	}
+	return a + "|" + b
+}
+
+func (d *Diplomacy) Propose(from, to, kind string, duration time.Duration) (routing.Treaty, error) {
+	if from == to {
+		return routing.Treaty{}, errors.New("you can't make a treaty with yourself")
+	}
+	if kind != routing.TreatyAlliance && kind != routing.TreatyCeasefire {
+		return routing.Treaty{}, fmt.Errorf("%s is not a kind of treaty", kind)
+	}
+	if kind == routing.TreatyCeasefire && duration <= 0 {
+		return routing.Treaty{}, errors.New("a ceasefire needs a duration")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if t, ok := d.treaties[pairKey(from, to)]; ok && treatyActive(t, time.Now()) {
+		return routing.Treaty{}, fmt.Errorf("there is already a %s", describeTreaty(t))
+	}
+	t := routing.Treaty{
+		Kind:     kind,
+		Proposer: from,
+		Partner:  to,
+		Duration: duration,
+	}
+	d.proposals[from+">"+to] = t
+	return t, nil
+}
+
+// Accept puts into force the proposal proposer made to username.
+func (d *Diplomacy) Accept(username, proposer string) (routing.Treaty, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := proposer + ">" + username
+	t, ok := d.proposals[key]
+	if !ok {
+		return routing.Treaty{}, fmt.Errorf("%s hasn't proposed a treaty to %s", proposer, username)
+	}
+	delete(d.proposals, key)
+	delete(d.proposals, username+">"+proposer)
+	if t.Kind == routing.TreatyCeasefire {
+		t.Expires = time.Now().Add(t.Duration)
+	}
+	d.treaties[pairKey(username, proposer)] = t
+	return t, nil
+}
+
+func (d *Diplomacy) Break(username, other, kind string) (routing.Treaty, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := pairKey(username, other)
+	t, ok := d.treaties[key]
+	if !ok || t.Kind != kind || !treatyActive(t, time.Now()) {
+		return routing.Treaty{}, fmt.Errorf("%s has no %s with %s", username, kind, other)
+	}
+	delete(d.treaties, key)
+	return t, nil
+}
+
+// Expire removes and returns the ceasefires that have run out.
+func (d *Diplomacy) Expire(now time.Time) []routing.Treaty {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	expired := []routing.Treaty{}
+	for key, t := range d.treaties {
+		if !treatyActive(t, now) {
+			expired = append(expired, t)
+			delete(d.treaties, key)
+		}
+	}
+	return expired
+}
+
+// Forget drops every treaty and proposal involving username.
+func (d *Diplomacy) Forget(username string) []routing.Treaty {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key, t := range d.proposals {
+		if t.Proposer == username || t.Partner == username {
+			delete(d.proposals, key)
+		}
+	}
+	dropped := []routing.Treaty{}
+	for key, t := range d.treaties {
+		if t.Proposer == username || t.Partner == username {
+			dropped = append(dropped, t)
+			delete(d.treaties, key)
+		}
+	}
+	return dropped
+}
+
+// Restore puts back the treaties in force when the server stopped.
+func (d *Diplomacy) Restore(treaties []routing.Treaty) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, t := range treaties {
+		d.treaties[pairKey(t.Proposer, t.Partner)] = t
+	}
+}
+
+func (d *Diplomacy) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.proposals = map[string]routing.Treaty{}
+	d.treaties = map[string]routing.Treaty{}
+}
+
+// AtPeace reports whether a and b have an alliance or a running ceasefire.
+func (d *Diplomacy) AtPeace(a, b string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	t, ok := d.treaties[pairKey(a, b)]
+	return ok && treatyActive(t, now)
+}
+
+func (d *Diplomacy) TreatiesOf(username string) []routing.Treaty {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	treaties := []routing.Treaty{}
+	for _, t := range d.treaties {
+		if t.Proposer == username || t.Partner == username {
+			treaties = append(treaties, t)
+		}
+	}
+	sort.Slice(treaties, func(i, j int) bool {
+		return treatyOther(treaties[i], username) < treatyOther(treaties[j], username)
+	})
+	return treaties
+}
+
+func (d *Diplomacy) All() []routing.Treaty {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	treaties := []routing.Treaty{}
+	for _, t := range d.treaties {
+		treaties = append(treaties, t)
+	}
+	sort.Slice(treaties, func(i, j int) bool {
+		return pairKey(treaties[i].Proposer, treaties[i].Partner) < pairKey(treaties[j].Proposer, treaties[j].Partner)
+	})
+	return treaties
+}
+
+// AtPeaceWith reports whether the player has an alliance or a running
+// ceasefire with username.
+func (gs *GameState) AtPeaceWith(username string) bool {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	now := time.Now()
+	for _, t := range gs.treaties {
+		if treatyOther(t, gs.Player.Username) == username && treatyActive(t, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// CommandPropose handles "propose alliance <user>" and
+// "propose ceasefire <user> <seconds>".
+func (gs *GameState) CommandPropose(words []string) (routing.DiplomacyRequest, error) {
+	usage := errors.New("usage: propose alliance <user> | propose ceasefire <user> <seconds>")
+	if len(words) < 3 {
+		return routing.DiplomacyRequest{}, usage
+	}
+	req := routing.DiplomacyRequest{
+		From:   gs.GetUsername(),
+		Action: routing.DiplomacyPropose,
+		Kind:   words[1],
+		Target: words[2],
+	}
+	switch req.Kind {
+	case routing.TreatyAlliance:
+		if len(words) != 3 {
+			return routing.DiplomacyRequest{}, usage
+		}
+	case routing.TreatyCeasefire:
+		if len(words) != 4 {
+			return routing.DiplomacyRequest{}, usage
+		}
+		seconds, err := strconv.Atoi(words[3])
+		if err != nil || seconds <= 0 {
+			return routing.DiplomacyRequest{}, fmt.Errorf("error: %s is not a number of seconds", words[3])
+		}
+		req.Duration = time.Duration(seconds) * time.Second
+	default:
+		return routing.DiplomacyRequest{}, usage
+	}
+	if req.Target == req.From {
+		return routing.DiplomacyRequest{}, errors.New("you can't make a treaty with yourself")
+	}
+	return req, nil
+}
+
+// CommandAccept handles "accept [user]". The user can be left out when
+// there's only one proposal waiting.
+func (gs *GameState) CommandAccept(words []string) (routing.DiplomacyRequest, error) {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	var proposer string
+	switch {
+	case len(words) == 2:
+		proposer = words[1]
+	case len(words) == 1 && len(gs.proposals) == 1:
+		for from := range gs.proposals {
+			proposer = from
+		}
+	case len(words) == 1 && len(gs.proposals) == 0:
+		return routing.DiplomacyRequest{}, errors.New("nobody has proposed a treaty to you")
+	default:
+		return routing.DiplomacyRequest{}, errors.New("usage: accept <user>")
+	}
+	return routing.DiplomacyRequest{
+		From:   gs.Player.Username,
+		Action: routing.DiplomacyAccept,
+		Target: proposer,
+	}, nil
+}
+
+// CommandBreak handles "break alliance <user>" and "break ceasefire <user>".
+func (gs *GameState) CommandBreak(words []string) (routing.DiplomacyRequest, error) {
+	if len(words) != 3 || (words[1] != routing.TreatyAlliance && words[1] != routing.TreatyCeasefire) {
+		return routing.DiplomacyRequest{}, errors.New("usage: break alliance <user> | break ceasefire <user>")
+	}
+	return routing.DiplomacyRequest{
+		From:   gs.GetUsername(),
+		Action: routing.DiplomacyBreak,
+		Kind:   words[1],
+		Target: words[2],
+	}, nil
+}
+
+func (gs *GameState) CommandTreaties() {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	now := time.Now()
+	if len(gs.treaties) == 0 {
+		fmt.Println("You have no treaties.")
+	}
+	for _, t := range gs.treaties {
+		if !treatyActive(t, now) {
+			continue
+		}
+		fmt.Printf("* %s\n", describeTreaty(t))
+	}
+	for from, t := range gs.proposals {
+		fmt.Printf("* %s proposed a(n) %s (accept %s)\n", from, t.Kind, from)
+	}
+}
+
+func (gs *GameState) HandleDiplomacy(du routing.DiplomacyUpdate) {
+	username := gs.GetUsername()
+	t := du.Treaty
+	party := t.Proposer == username || t.Partner == username
+	if party && du.Treaties == nil {
+		// public announcement of our own treaty, we already got the private
+		// update with the full picture
+		return
+	}
+
+	gs.mu.Lock()
+	if du.Treaties != nil {
+		gs.treaties = du.Treaties
+	}
+	switch du.Event {
+	case routing.DiplomacyProposed:
+		if t.Partner == username {
+			gs.proposals[t.Proposer] = t
+		}
+	case routing.DiplomacyAccepted:
+		delete(gs.proposals, t.Proposer)
+	}
+	gs.mu.Unlock()
+
+	defer fmt.Println("------------------------")
+	fmt.Println()
+	fmt.Println("==== Diplomacy ====")
+	switch du.Event {
+	case routing.DiplomacyProposed:
+		if t.Partner == username {
+			fmt.Printf("%s proposes a(n) %s. Type \"accept %s\" to agree.\n", t.Proposer, t.Kind, t.Proposer)
+			if t.Kind == routing.TreatyCeasefire {
+				fmt.Printf("The ceasefire would last %v.\n", t.Duration)
+			}
+		} else {
+			fmt.Printf("You proposed a(n) %s to %s.\n", t.Kind, t.Partner)
+		}
+	case routing.DiplomacyAccepted:
+		fmt.Printf("There is now a %s.\n", describeTreaty(t))
+	case routing.DiplomacyBroken:
+		fmt.Printf("The %s has been broken: %s\n", describeTreaty(t), du.Reason)
+	case routing.DiplomacyExpired:
+		fmt.Printf("The %s has expired.\n", describeTreaty(t))
+	case routing.DiplomacyRejected:
+		fmt.Printf("Your diplomacy request was refused: %s\n", du.Reason)
+	}
+}
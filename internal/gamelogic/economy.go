@@ -0,0 +1,126 @@
+package gamelogic
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+const (
+	StartingBalance    = 10
+	IncomePerTerritory = 2
+)
+
+// EconomyTick is how often income is paid outside of turn-based mode.
+const EconomyTick = 15 * time.Second
+
+func RankCost(rank UnitRank) int {
+	switch rank {
+	case RankArtillery:
+		return 10
+	case RankCavalry:
+		return 5
+	case RankInfantry:
+		return 2
+	}
+	return 0
+}
+
+// Income is what a player earns per tick or turn for the territories their
+// units hold.
+func Income(units []Unit) int {
+	territories := map[Location]struct{}{}
+	for _, unit := range units {
+		territories[unit.Location] = struct{}{}
+	}
+	return len(territories) * IncomePerTerritory
+}
+
+func NewIncomeEvent(game string, income map[string]int) GameEvent {
+	ev := newEvent(EventIncome, game, "")
+	ev.Income = income
+	return ev
+}
+
+func (gs *GameState) GetBalance() int {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.balance
+}
+
+func (gs *GameState) GetIncome() int {
+	return Income(gs.getUnitsSnap())
+}
+
+func (gs *GameState) canAfford(rank UnitRank) error {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	cost := RankCost(rank)
+	if gs.balance < cost {
+		return fmt.Errorf("you can't afford a(n) %s: it costs %d and your balance is %d", rank, cost, gs.balance)
+	}
+	return nil
+}
+
+func (gs *GameState) spend(amount int) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.balance -= amount
+}
+
+func (gs *GameState) earn(amount int) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.balance += amount
+}
+
+// HandleEconomyUpdate replaces the local balance with the server's and undoes
+// any spawn the server refused.
+func (gs *GameState) HandleEconomyUpdate(eu routing.EconomyUpdate) {
+	gs.mu.Lock()
+	gs.balance = eu.Balance
+	gs.mu.Unlock()
+
+	if eu.RejectedUnitID == 0 {
+		return
+	}
+	defer fmt.Println("------------------------")
+	fmt.Println()
+	fmt.Println("==== Spawn Rejected ====")
+	fmt.Println(eu.Reason)
+	gs.removeUnit(eu.RejectedUnitID)
+	fmt.Printf("Unit %d has been removed. Your balance is %d.\n", eu.RejectedUnitID, eu.Balance)
+}
+
+// CheckSpawn reports whether the player can afford the unit in ev. Events
+// that aren't spawns are always affordable.
+func (w *World) CheckSpawn(ev GameEvent) error {
+	if ev.Type != EventSpawn || ev.Unit == nil {
+		return nil
+	}
+	return w.Player(ev.Username).canAfford(ev.Unit.Rank)
+}
+
+// CollectIncome works out what every player earns this tick.
+func (w *World) CollectIncome() map[string]int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	income := map[string]int{}
+	for name, gs := range w.Players {
+		income[name] = Income(gs.getUnitsSnap())
+	}
+	return income
+}
+
+// Balance is what username has to spend. Someone who hasn't played yet
+// has the starting balance.
+func (w *World) Balance(username string) int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	gs, ok := w.Players[username]
+	if !ok {
+		return StartingBalance
+	}
+	return gs.GetBalance()
+}
@@ -0,0 +1,211 @@
+package gamelogic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+const eventsExt = ".jsonl"
+
+// eventsDir is where each game's events are stored, one file per game.
+var eventsDir = "events"
+
+type EventType string
+
+const (
+	EventSpawn       EventType = "spawn"
+	EventMove        EventType = "move"
+	EventWarDeclared EventType = "war_declared"
+	EventWarOutcome  EventType = "war_outcome"
+	EventIncome      EventType = "income"
+	EventGameOver    EventType = "game_over"
+	EventPlayerLeft  EventType = "player_left"
+	EventReset       EventType = "reset"
+	EventPause       EventType = "pause"
+	EventResume      EventType = "resume"
+	EventDeadline    EventType = "deadline"
+	// EventTreatySigned and EventTreatyEnded keep the server's treaties
+	// across restarts.
+	EventTreatySigned EventType = "treaty_signed"
+	EventTreatyEnded  EventType = "treaty_ended"
+)
+
+// GameEvent is a single domain event in a game's history. Only the field
+// matching Type is set.
+type GameEvent struct {
+	Type     EventType
+	Time     time.Time
+	Game     string
+	Username string
+	Unit     *Unit             `json:",omitempty"`
+	Move     *ArmyMove         `json:",omitempty"`
+	War      *RecognitionOfWar `json:",omitempty"`
+	Result   *WarResult        `json:",omitempty"`
+	Income   map[string]int    `json:",omitempty"`
+	GameOver *routing.GameOver `json:",omitempty"`
+	Deadline *time.Time        `json:",omitempty"`
+	Treaty   *routing.Treaty   `json:",omitempty"`
+	// Seq is where the event is in the game's log, counting from 1. The
+	// server sets it when recording, so followers can tell which events
+	// they have already seen.
+	Seq int `json:",omitempty"`
+}
+
+type WarResult struct {
+	Location Location
+	Winner   string
+	Loser    string
+	Draw     bool
+}
+
+func newEvent(t EventType, game, username string) GameEvent {
+	return GameEvent{
+		Type:     t,
+		Time:     time.Now(),
+		Game:     game,
+		Username: username,
+	}
+}
+
+func NewSpawnEvent(game, username string, unit Unit) GameEvent {
+	ev := newEvent(EventSpawn, game, username)
+	ev.Unit = &unit
+	return ev
+}
+
+func NewMoveEvent(game string, move ArmyMove) GameEvent {
+	ev := newEvent(EventMove, game, move.Player.Username)
+	ev.Move = &move
+	return ev
+}
+
+func NewWarDeclaredEvent(game, username string, rw RecognitionOfWar) GameEvent {
+	ev := newEvent(EventWarDeclared, game, username)
+	ev.War = &rw
+	return ev
+}
+
+func NewWarOutcomeEvent(game, username string, rw RecognitionOfWar, outcome WarOutcome, winner, loser string) GameEvent {
+	ev := newEvent(EventWarOutcome, game, username)
+	ev.Result = &WarResult{
+		Location: getOverlappingLocation(rw.Attacker, rw.Defender),
+		Winner:   winner,
+		Loser:    loser,
+		Draw:     outcome == WarOutcomeDraw,
+	}
+	return ev
+}
+
+func NewPauseEvent(game string, paused bool) GameEvent {
+	if paused {
+		return newEvent(EventPause, game, "")
+	}
+	return newEvent(EventResume, game, "")
+}
+
+// NewTreatyEvent records t coming into force, or ending if signed is false.
+func NewTreatyEvent(game string, t routing.Treaty, signed bool) GameEvent {
+	ev := newEvent(EventTreatyEnded, game, "")
+	if signed {
+		ev.Type = EventTreatySigned
+	}
+	ev.Treaty = &t
+	return ev
+}
+
+var eventsMu sync.Mutex
+
+func eventsFile(game string) string {
+	return filepath.Join(eventsDir, game+eventsExt)
+}
+
+// StoredGames lists the games that have recorded events.
+func StoredGames() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(eventsDir, "*"+eventsExt))
+	if err != nil {
+		return nil, err
+	}
+	games := []string{}
+	for _, m := range matches {
+		games = append(games, strings.TrimSuffix(filepath.Base(m), eventsExt))
+	}
+	return games, nil
+}
+
+// AppendEvent adds ev to the end of its game's event store. Events are never
+// modified or removed once written.
+func AppendEvent(ev GameEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("could not marshal event: %v", err)
+	}
+
+	eventsMu.Lock()
+	defer eventsMu.Unlock()
+
+	err = os.MkdirAll(eventsDir, 0755)
+	if err != nil {
+		return fmt.Errorf("could not create events directory: %v", err)
+	}
+	f, err := os.OpenFile(eventsFile(ev.Game), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open events file: %v", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	if err != nil {
+		return fmt.Errorf("could not write to events file: %v", err)
+	}
+	return nil
+}
+
+// LoadEvents reads the events recorded for game, in the order they were
+// stored. Events after until are skipped unless until is the zero time. A
+// last line cut short, e.g. by a crash while it was written, is skipped.
+func LoadEvents(game string, until time.Time) ([]GameEvent, error) {
+	eventsMu.Lock()
+	defer eventsMu.Unlock()
+
+	f, err := os.Open(eventsFile(game))
+	if err != nil {
+		return nil, fmt.Errorf("could not open events file: %w", err)
+	}
+	defer f.Close()
+
+	events := []GameEvent{}
+	r := bufio.NewReader(f)
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		last := errors.Is(err, io.EOF)
+		if err != nil && !last {
+			return nil, fmt.Errorf("could not read events file: %v", err)
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			var ev GameEvent
+			err = json.Unmarshal(line, &ev)
+			switch {
+			case err != nil && last:
+				fmt.Printf("Skipping unfinished event at the end of %s\n", eventsFile(game))
+			case err != nil:
+				return nil, fmt.Errorf("could not parse event on line %d: %v", n, err)
+			case until.IsZero() || !ev.Time.After(until):
+				events = append(events, ev)
+			}
+		}
+		if last {
+			return events, nil
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package gamelogic
+
+// borders are the pairs of territories next to each other. A border works
+// both ways.
+var borders = [][2]Location{
+	{"americas", "europe"},
+	{"americas", "asia"},
+	{"americas", "antarctica"},
+	{"europe", "africa"},
+	{"europe", "asia"},
+	{"africa", "asia"},
+	{"africa", "antarctica"},
+	{"asia", "australia"},
+	{"australia", "antarctica"},
+}
+
+func AdjacentLocations(loc Location) []Location {
+	adjacent := []Location{}
+	for _, b := range borders {
+		if b[0] == loc {
+			adjacent = append(adjacent, b[1])
+		} else if b[1] == loc {
+			adjacent = append(adjacent, b[0])
+		}
+	}
+	return adjacent
+}
+
+// visibleLocations are the territories p has units in, and their neighbours.
+func visibleLocations(p Player) map[Location]struct{} {
+	visible := map[Location]struct{}{}
+	for _, unit := range p.Units {
+		visible[unit.Location] = struct{}{}
+		for _, loc := range AdjacentLocations(unit.Location) {
+			visible[loc] = struct{}{}
+		}
+	}
+	return visible
+}
+
+// FilterMove returns what viewer can see of move under fog of war. from holds
+// the territories the moving units left. The mover's army is cut down to the
+// units viewer can see, and ok is false if viewer can't see the move at all.
+func FilterMove(move ArmyMove, from []Location, viewer Player) (view ArmyMove, ok bool) {
+	visible := visibleLocations(viewer)
+	_, ok = visible[move.ToLocation]
+	for _, loc := range from {
+		if _, seen := visible[loc]; seen {
+			ok = true
+		}
+	}
+	if !ok {
+		return ArmyMove{}, false
+	}
+
+	units := map[int]Unit{}
+	for id, unit := range move.Player.Units {
+		if _, seen := visible[unit.Location]; seen {
+			units[id] = unit
+		}
+	}
+	view = ArmyMove{
+		Player: Player{
+			Username: move.Player.Username,
+			Units:    units,
+		},
+		Units:      move.Units,
+		ToLocation: move.ToLocation,
+		Arrival:    move.Arrival,
+	}
+	return view, true
+}
+
+// FogViews works out what every other player can see of move. It has to be
+// called before the move is applied, while the world still knows where the
+// units came from.
+func (w *World) FogViews(move ArmyMove) map[string]ArmyMove {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	from := []Location{}
+	if mover, ok := w.Players[move.Player.Username]; ok {
+		for _, unit := range move.Units {
+			if old, ok := mover.GetUnit(unit.ID); ok {
+				from = append(from, old.Location)
+			}
+		}
+	}
+
+	views := map[string]ArmyMove{}
+	for _, name := range w.usernames() {
+		if name == move.Player.Username {
+			continue
+		}
+		view, ok := FilterMove(move, from, w.Players[name].GetPlayerSnap())
+		if ok {
+			views[name] = view
+		}
+	}
+	return views
+}
+
+func (gs *GameState) SetFogOfWar(fog bool) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.fog = fog
+}
+
+func (gs *GameState) IsFogOfWar() bool {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.fog
+}
@@ -0,0 +1,43 @@
+package gamelogic
+
+import "testing"
+
+func TestFilterMoveArrivalMakesWar(t *testing.T) {
+	defender := NewGameState("defender")
+	defender.addUnit(Unit{ID: 1, Rank: RankInfantry, Location: "asia"})
+
+	attacker := NewGameState("attacker")
+	attacker.addUnit(Unit{ID: 1, Rank: RankCavalry, Location: "asia"})
+	move := ArmyMove{
+		Player:     attacker.GetPlayerSnap(),
+		Units:      []Unit{{ID: 1, Rank: RankCavalry, Location: "asia"}},
+		ToLocation: "asia",
+		Arrival:    true,
+	}
+
+	view, ok := FilterMove(move, []Location{"europe"}, defender.GetPlayerSnap())
+	if !ok {
+		t.Fatal("the defender should see a move into their territory")
+	}
+	if !view.Arrival {
+		t.Fatal("the filtered view lost the arrival")
+	}
+	if outcome := defender.HandleMove(view); outcome != MoveOutcomeMakeWar {
+		t.Errorf("HandleMove() = %v, want MoveOutcomeMakeWar", outcome)
+	}
+}
+
+func TestFilterMoveHidden(t *testing.T) {
+	viewer := NewGameState("viewer")
+	viewer.addUnit(Unit{ID: 1, Rank: RankInfantry, Location: "australia"})
+
+	move := ArmyMove{
+		Player:     Player{Username: "mover", Units: map[int]Unit{1: {ID: 1, Location: "africa"}}},
+		Units:      []Unit{{ID: 1, Location: "africa"}},
+		ToLocation: "africa",
+		Arrival:    true,
+	}
+	if _, ok := FilterMove(move, []Location{"europe"}, viewer.GetPlayerSnap()); ok {
+		t.Error("a move far from the viewer's units should be hidden")
+	}
+}
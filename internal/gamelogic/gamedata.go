@@ -1,5 +1,10 @@
 package gamelogic
 
+import (
+	"sort"
+	"time"
+)
+
 type Player struct {
 	Username string
 	Units    map[int]Unit
@@ -17,12 +22,22 @@ type Unit struct {
 	ID       int
 	Rank     UnitRank
 	Location Location
+	// Veterancy is earned by surviving winning wars and adds to the unit's
+	// power.
+	Veterancy int `json:",omitempty"`
+	// Destination and Remaining are set while the unit is in transit. It
+	// stays in Location until it lands.
+	Destination Location      `json:",omitempty"`
+	Remaining   time.Duration `json:",omitempty"`
 }
 
+// ArmyMove is sent when units set off and again when they land. Only
+// arrivals can start a war.
 type ArmyMove struct {
 	Player     Player
 	Units      []Unit
 	ToLocation Location
+	Arrival    bool
 }
 
 type RecognitionOfWar struct {
@@ -50,3 +65,23 @@ func getAllLocations() map[Location]struct{} {
 		"antarctica": {},
 	}
 }
+
+// Ranks lists every unit rank, cheapest first.
+func Ranks() []UnitRank {
+	ranks := []UnitRank{}
+	for rank := range getAllRanks() {
+		ranks = append(ranks, rank)
+	}
+	sort.Slice(ranks, func(i, j int) bool { return RankCost(ranks[i]) < RankCost(ranks[j]) })
+	return ranks
+}
+
+// Locations lists every territory on the map, in alphabetical order.
+func Locations() []Location {
+	locations := []Location{}
+	for loc := range getAllLocations() {
+		locations = append(locations, loc)
+	}
+	sort.Slice(locations, func(i, j int) bool { return locations[i] < locations[j] })
+	return locations
+}
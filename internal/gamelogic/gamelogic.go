@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
 	"strings"
@@ -17,10 +18,31 @@ func PrintClientHelp() {
 	fmt.Println("* spawn <location> <rank>")
 	fmt.Println("    example:")
 	fmt.Println("    spawn europe infantry")
+	fmt.Println("    costs: infantry 2, cavalry 5, artillery 10")
 	fmt.Println("* status")
+	fmt.Println("* say <message>")
+	fmt.Println("    example:")
+	fmt.Println("    say good luck everyone")
+	fmt.Println("* whisper <user> <message>")
+	fmt.Println("    example:")
+	fmt.Println("    whisper bob let's team up against alice")
+	fmt.Println("* propose alliance <user>")
+	fmt.Println("* propose ceasefire <user> <seconds>")
+	fmt.Println("    example:")
+	fmt.Println("    propose ceasefire bob 120")
+	fmt.Println("* accept [user]")
+	fmt.Println("* break alliance <user>")
+	fmt.Println("* break ceasefire <user>")
+	fmt.Println("* treaties")
+	fmt.Println("* orders")
+	fmt.Println("    list the orders queued for this turn (turn-based mode)")
+	fmt.Println("* submit")
+	fmt.Println("    send your queued orders for this turn (turn-based mode)")
 	fmt.Println("* spam <n>")
 	fmt.Println("    example:")
 	fmt.Println("    spam 5")
+	fmt.Println("* leave")
+	fmt.Println("    go back to the lobby")
 	fmt.Println("* quit")
 	fmt.Println("* help")
 }
@@ -34,28 +56,82 @@ func ClientWelcome() (string, error) {
 	}
 	username := words[0]
 	fmt.Printf("Welcome, %s!\n", username)
-	PrintClientHelp()
 	return username, nil
 }
 
+func PrintLobbyHelp() {
+	fmt.Println("You are in the lobby. Possible commands:")
+	fmt.Println("* games")
+	fmt.Println("* create <game> [fog]")
+	fmt.Println("* join <game>")
+	fmt.Println("    example:")
+	fmt.Println("    join default")
+	fmt.Println("* quit")
+	fmt.Println("* help")
+}
+
 func PrintServerHelp() {
 	fmt.Println("Possible commands:")
+	fmt.Println("* games")
+	fmt.Println("* create <game> [fog]")
+	fmt.Println("* use <game>")
+	fmt.Println("    the commands below act on the game in use, apart from replay")
 	fmt.Println("* pause")
 	fmt.Println("* resume")
+	fmt.Println("* replay <game> [until <time>]")
+	fmt.Println("    example:")
+	fmt.Println("    replay default until 2024-05-01T12:00:00Z")
+	fmt.Println("* players")
+	fmt.Println("* kick <user>")
+	fmt.Println("* reset")
+	fmt.Println("* broadcast <message>")
+	fmt.Println("    example:")
+	fmt.Println("    broadcast the server restarts in 5 minutes")
+	fmt.Println("* treaties")
+	fmt.Println("* standings")
+	fmt.Println("* turns start [seconds]")
+	fmt.Println("* turns stop")
+	fmt.Println("* dlq list")
+	fmt.Println("* dlq show <n>")
+	fmt.Println("* dlq requeue <n|all>")
+	fmt.Println("* dlq purge")
 	fmt.Println("* quit")
 	fmt.Println("* help")
 }
 
-func GetInput() []string {
+// stdin is shared by every read, so nothing typed ahead is lost in between.
+var stdin = bufio.NewScanner(os.Stdin)
+
+// readLine prompts for a line and reads it. It returns false once there is
+// no more input.
+var readLine = func() (string, bool) {
 	fmt.Print("> ")
-	scanner := bufio.NewScanner(os.Stdin)
-	scanned := scanner.Scan()
-	if !scanned {
-		return nil
+	if !stdin.Scan() {
+		return "", false
+	}
+	return stdin.Text(), true
+}
+
+// SetLineReader makes GetInput read through read, e.g. a line editor, which
+// is then in charge of the prompt.
+func SetLineReader(read func() (string, bool)) {
+	readLine = read
+}
+
+func GetInput() []string {
+	words, _ := ReadInput()
+	return words
+}
+
+// ReadInput is GetInput that also says whether there was anything left to
+// read, so a REPL can stop when stdin is closed.
+func ReadInput() ([]string, bool) {
+	line, ok := readLine()
+	if !ok {
+		return nil, false
 	}
-	line := scanner.Text()
 	line = strings.TrimSpace(line)
-	return strings.Fields(line)
+	return strings.Fields(line), true
 }
 
 func GetMaliciousLog() string {
@@ -77,16 +153,38 @@ func PrintQuit() {
 }
 
 func (gs *GameState) CommandStatus() {
+	gs.WriteStatus(os.Stdout)
+}
+
+// WriteStatus writes what the status command shows to w.
+func (gs *GameState) WriteStatus(w io.Writer) {
+	gs.mu.RLock()
+	over := gs.gameOver
+	gs.mu.RUnlock()
+	if over != nil {
+		fmt.Fprintln(w, "The game is over.")
+		printGameOver(w, *over)
+	}
+
 	if gs.isPaused() {
-		fmt.Println("The game is paused.")
+		fmt.Fprintln(w, "The game is paused.")
 		return
 	} else {
-		fmt.Println("The game is not paused.")
+		fmt.Fprintln(w, "The game is not paused.")
+	}
+
+	if gs.IsFogOfWar() {
+		fmt.Fprintln(w, "Fog of war is on, you only see moves near your units.")
 	}
 
 	p := gs.GetPlayerSnap()
-	fmt.Printf("You are %s, and you have %d units.\n", p.Username, len(p.Units))
+	fmt.Fprintf(w, "You are %s, and you have %d units.\n", p.Username, len(p.Units))
+	fmt.Fprintf(w, "Your balance is %d, earning %d per tick.\n", gs.GetBalance(), gs.GetIncome())
 	for _, unit := range p.Units {
-		fmt.Printf("* %v: %v, %v\n", unit.ID, unit.Location, unit.Rank)
+		if unit.InTransit() {
+			fmt.Fprintf(w, "* %v: %v, %v, veterancy %v, in transit to %v (%v left)\n", unit.ID, unit.Location, unit.Rank, unit.Veterancy, unit.Destination, unit.Remaining)
+			continue
+		}
+		fmt.Fprintf(w, "* %v: %v, %v, veterancy %v\n", unit.ID, unit.Location, unit.Rank, unit.Veterancy)
 	}
 }
@@ -2,12 +2,25 @@ package gamelogic
 
 import (
 	"sync"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
 )
 
 type GameState struct {
-	Player Player
-	Paused bool
-	mu     *sync.RWMutex
+	Player    Player
+	Paused    bool
+	balance   int
+	turn      turnState
+	gameOver  *routing.GameOver
+	treaties  []routing.Treaty
+	proposals map[string]routing.Treaty
+	fog       bool
+	// others are the other players we know to be in the game.
+	others map[string]struct{}
+	// lastUnitID is the highest unit ID ever used, so IDs of dead units
+	// aren't handed out again.
+	lastUnitID int
+	mu         *sync.RWMutex
 }
 
 func NewGameState(username string) *GameState {
@@ -16,8 +29,11 @@ func NewGameState(username string) *GameState {
 			Username: username,
 			Units:    map[int]Unit{},
 		},
-		Paused: false,
-		mu:     &sync.RWMutex{},
+		Paused:    false,
+		balance:   StartingBalance,
+		proposals: map[string]routing.Treaty{},
+		others:    map[string]struct{}{},
+		mu:        &sync.RWMutex{},
 	}
 }
 
@@ -39,10 +55,29 @@ func (gs *GameState) isPaused() bool {
 	return gs.Paused
 }
 
+func (gs *GameState) IsPaused() bool {
+	return gs.isPaused()
+}
+
 func (gs *GameState) addUnit(u Unit) {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
 	gs.Player.Units[u.ID] = u
+	if u.ID > gs.lastUnitID {
+		gs.lastUnitID = u.ID
+	}
+}
+
+func (gs *GameState) removeUnit(id int) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	delete(gs.Player.Units, id)
+}
+
+func (gs *GameState) removeAllUnits() {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.Player.Units = map[int]Unit{}
 }
 
 func (gs *GameState) removeUnitsInLocation(loc Location) {
@@ -55,6 +90,14 @@ func (gs *GameState) removeUnitsInLocation(loc Location) {
 	}
 }
 
+// nextUnitID is one more than the highest unit ID ever used, so a new unit
+// never takes the ID of another, even one that has died.
+func (gs *GameState) nextUnitID() int {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.lastUnitID + 1
+}
+
 func (gs *GameState) UpdateUnit(u Unit) {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
@@ -0,0 +1,26 @@
+package gamelogic
+
+import (
+	"fmt"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+func PrintGames(games []routing.GameInfo) {
+	if len(games) == 0 {
+		fmt.Println("There are no games.")
+		return
+	}
+	for _, g := range games {
+		status := "running"
+		if g.Over {
+			status = "over"
+		} else if g.Paused {
+			status = "paused"
+		}
+		if g.Fog {
+			status += ", fog of war"
+		}
+		fmt.Printf("* %s (%s): %d player(s) %v\n", g.ID, status, len(g.Players), g.Players)
+	}
+}
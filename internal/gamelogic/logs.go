@@ -1,32 +1,101 @@
 package gamelogic
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
 )
 
-const logsFile = "game.log"
+// LogsFile and WriteToDiskSleep can be changed through the config package.
+var LogsFile = "game.log"
 
-const writeToDiskSleep = 1 * time.Second
+var WriteToDiskSleep = 1 * time.Second
 
 func WriteLog(gamelog routing.GameLog) error {
 	log.Printf("received game log...")
-	time.Sleep(writeToDiskSleep)
+	time.Sleep(WriteToDiskSleep)
 
-	f, err := os.OpenFile(logsFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	f, err := os.OpenFile(LogsFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return fmt.Errorf("could not open logs file: %v", err)
 	}
 	defer f.Close()
 
-	str := fmt.Sprintf("%v %v: %v\n", gamelog.CurrentTime.Format(time.RFC3339), gamelog.Username, gamelog.Message)
+	str := fmt.Sprintf("%v [%v] %v: %v\n", gamelog.CurrentTime.Format(time.RFC3339), gamelog.Game, gamelog.Username, gamelog.Message)
 	_, err = f.WriteString(str)
 	if err != nil {
 		return fmt.Errorf("could not write to logs file: %v", err)
 	}
 	return nil
 }
+
+// ReadLogs reads back what WriteLog wrote, oldest first. Empty filters
+// match everything, and a zero since goes back to the start.
+func ReadLogs(game, username string, since time.Time) ([]routing.GameLog, error) {
+	logs := []routing.GameLog{}
+	f, err := os.Open(LogsFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return logs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not open logs file: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		gl, ok := parseLog(scanner.Text())
+		if !ok {
+			continue
+		}
+		if (game != "" && gl.Game != game) || (username != "" && gl.Username != username) {
+			continue
+		}
+		if gl.CurrentTime.Before(since) {
+			continue
+		}
+		logs = append(logs, gl)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read logs file: %v", err)
+	}
+	return logs, nil
+}
+
+// parseLog undoes the formatting in WriteLog. Lines written before logs
+// had a game have no "[game]" and come back with an empty Game.
+func parseLog(line string) (routing.GameLog, bool) {
+	stamp, rest, ok := strings.Cut(line, " ")
+	if !ok {
+		return routing.GameLog{}, false
+	}
+	t, err := time.Parse(time.RFC3339, stamp)
+	if err != nil {
+		return routing.GameLog{}, false
+	}
+	game := ""
+	if strings.HasPrefix(rest, "[") {
+		game, rest, ok = strings.Cut(rest[1:], "] ")
+		if !ok {
+			return routing.GameLog{}, false
+		}
+	}
+	username, msg, ok := strings.Cut(rest, ": ")
+	if !ok {
+		return routing.GameLog{}, false
+	}
+	gl := routing.GameLog{
+		CurrentTime: t,
+		Message:     msg,
+		Username:    username,
+		Game:        game,
+	}
+	return gl, true
+}
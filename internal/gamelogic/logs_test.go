@@ -0,0 +1,102 @@
+package gamelogic
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+func TestParseLog(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		line string
+		want routing.GameLog
+		ok   bool
+	}{
+		{
+			line: "2024-05-01T12:00:00Z [main] alice: alice won a war against bob",
+			want: routing.GameLog{CurrentTime: at, Game: "main", Username: "alice", Message: "alice won a war against bob"},
+			ok:   true,
+		},
+		{
+			line: "2024-05-01T12:00:00Z [main] alice: time: 12:00",
+			want: routing.GameLog{CurrentTime: at, Game: "main", Username: "alice", Message: "time: 12:00"},
+			ok:   true,
+		},
+		{
+			// written before logs had a game
+			line: "2024-05-01T12:00:00Z alice: All warfare is based on deception.",
+			want: routing.GameLog{CurrentTime: at, Username: "alice", Message: "All warfare is based on deception."},
+			ok:   true,
+		},
+		{line: "", ok: false},
+		{line: "yesterday [main] alice: hi", ok: false},
+		{line: "2024-05-01T12:00:00Z [main alice: hi", ok: false},
+		{line: "2024-05-01T12:00:00Z [main] alice", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := parseLog(tt.line)
+		if ok != tt.ok || !got.CurrentTime.Equal(tt.want.CurrentTime) || got.Game != tt.want.Game ||
+			got.Username != tt.want.Username || got.Message != tt.want.Message {
+			t.Errorf("parseLog(%q) = %+v, %v, want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestWriteLogReadLogs(t *testing.T) {
+	oldFile, oldSleep := LogsFile, WriteToDiskSleep
+	t.Cleanup(func() { LogsFile, WriteToDiskSleep = oldFile, oldSleep })
+	LogsFile = filepath.Join(t.TempDir(), "game.log")
+	WriteToDiskSleep = 0
+
+	err := os.WriteFile(LogsFile, []byte("2024-05-01T11:00:00Z alice: an old line\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	written := []routing.GameLog{
+		{CurrentTime: at, Game: "main", Username: "alice", Message: "first"},
+		{CurrentTime: at.Add(time.Minute), Game: "other", Username: "bob", Message: "second"},
+		{CurrentTime: at.Add(2 * time.Minute), Game: "main", Username: "bob", Message: "third"},
+	}
+	for _, gl := range written {
+		err := WriteLog(gl)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		game, user string
+		since      time.Time
+		want       []string
+	}{
+		{"", "", time.Time{}, []string{"an old line", "first", "second", "third"}},
+		{"main", "", time.Time{}, []string{"first", "third"}},
+		{"", "bob", time.Time{}, []string{"second", "third"}},
+		{"", "", at.Add(time.Minute), []string{"second", "third"}},
+	}
+	for _, tt := range tests {
+		logs, err := ReadLogs(tt.game, tt.user, tt.since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, gl := range logs {
+			got = append(got, gl.Message)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("ReadLogs(%q, %q, %v) = %v, want %v", tt.game, tt.user, tt.since, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("ReadLogs(%q, %q, %v) = %v, want %v", tt.game, tt.user, tt.since, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -20,7 +20,11 @@ func (gs *GameState) HandleMove(move ArmyMove) MoveOutcome {
 
 	fmt.Println()
 	fmt.Println("==== Move Detected ====")
-	fmt.Printf("%s is moving %v unit(s) to %s\n", move.Player.Username, len(move.Units), move.ToLocation)
+	if move.Arrival {
+		fmt.Printf("%s moved %v unit(s) into %s\n", move.Player.Username, len(move.Units), move.ToLocation)
+	} else {
+		fmt.Printf("%s is sending %v unit(s) to %s\n", move.Player.Username, len(move.Units), move.ToLocation)
+	}
 	for _, unit := range move.Units {
 		fmt.Printf("* %v\n", unit.Rank)
 	}
@@ -28,8 +32,17 @@ func (gs *GameState) HandleMove(move ArmyMove) MoveOutcome {
 	if player.Username == move.Player.Username {
 		return MoveOutcomeSamePlayer
 	}
+	gs.SeePlayers(move.Player.Username)
+	if !move.Arrival {
+		fmt.Println("They are still on their way.")
+		return MoveOutComeSafe
+	}
 
 	overlappingLocation := getOverlappingLocation(player, move.Player)
+	if overlappingLocation != "" && gs.AtPeaceWith(move.Player.Username) {
+		fmt.Printf("You share %s with %s, but you are at peace.\n", overlappingLocation, move.Player.Username)
+		return MoveOutComeSafe
+	}
 	if overlappingLocation != "" {
 		fmt.Printf("You have units in %s! You are at war with %s!\n", overlappingLocation, move.Player.Username)
 		return MoveOutcomeMakeWar
@@ -53,22 +66,9 @@ func (gs *GameState) CommandMove(words []string) (ArmyMove, error) {
 	if gs.isPaused() {
 		return ArmyMove{}, errors.New("the game is paused, you can not move units")
 	}
-	if len(words) < 3 {
-		return ArmyMove{}, errors.New("usage: move <location> <unitID> <unitID> <unitID> etc")
-	}
-	newLocation := Location(words[1])
-	locations := getAllLocations()
-	if _, ok := locations[newLocation]; !ok {
-		return ArmyMove{}, fmt.Errorf("error: %s is not a valid location", newLocation)
-	}
-	unitIDs := []int{}
-	for _, word := range words[2:] {
-		id := word
-		unitID, err := strconv.Atoi(id)
-		if err != nil {
-			return ArmyMove{}, fmt.Errorf("error: %s is not a valid unit ID", id)
-		}
-		unitIDs = append(unitIDs, unitID)
+	newLocation, unitIDs, err := parseMove(words)
+	if err != nil {
+		return ArmyMove{}, err
 	}
 
 	newUnits := []Unit{}
@@ -77,16 +77,48 @@ func (gs *GameState) CommandMove(words []string) (ArmyMove, error) {
 		if !ok {
 			return ArmyMove{}, fmt.Errorf("error: unit with ID %v not found", unitID)
 		}
-		unit.Location = newLocation
-		gs.UpdateUnit(unit)
+		if unit.InTransit() {
+			return ArmyMove{}, fmt.Errorf("error: unit with ID %v is already on its way to %s", unitID, unit.Destination)
+		}
 		newUnits = append(newUnits, unit)
 	}
 
+	for i, unit := range newUnits {
+		unit.Destination = newLocation
+		unit.Remaining = TravelTime(unit.Rank, unit.Location, newLocation)
+		gs.UpdateUnit(unit)
+		newUnits[i] = unit
+	}
+
 	mv := ArmyMove{
 		ToLocation: newLocation,
 		Units:      newUnits,
 		Player:     gs.GetPlayerSnap(),
 	}
-	fmt.Printf("Moved %v units to %s\n", len(mv.Units), mv.ToLocation)
+	fmt.Printf("Sent %v units to %s\n", len(mv.Units), mv.ToLocation)
+	for _, unit := range mv.Units {
+		fmt.Printf("* %v: %v arrives in %v\n", unit.ID, unit.Rank, unit.Remaining)
+	}
 	return mv, nil
 }
+
+func parseMove(words []string) (Location, []int, error) {
+	if len(words) < 3 {
+		return "", nil, errors.New("usage: move <location> <unitID> <unitID> <unitID> etc")
+	}
+	newLocation := Location(words[1])
+	locations := getAllLocations()
+	if _, ok := locations[newLocation]; !ok {
+		return "", nil, fmt.Errorf("error: %s is not a valid location", newLocation)
+	}
+	unitIDs := []int{}
+	for _, word := range words[2:] {
+		id := word
+		unitID, err := strconv.Atoi(id)
+		if err != nil {
+			return "", nil, fmt.Errorf("error: %s is not a valid unit ID", id)
+		}
+		unitIDs = append(unitIDs, unitID)
+	}
+	return newLocation, unitIDs, nil
+}
@@ -0,0 +1,57 @@
+package gamelogic
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+func (gs *GameState) HandlePlayerJoined(pj routing.PlayerJoined) {
+	defer fmt.Println("------------------------")
+	fmt.Println()
+	fmt.Println("==== Player Joined ====")
+	if pj.Username == gs.GetUsername() {
+		fmt.Printf("You have joined %s.\n", pj.Game)
+		return
+	}
+	gs.SeePlayers(pj.Username)
+	fmt.Printf("%s has joined %s.\n", pj.Username, pj.Game)
+}
+
+func (gs *GameState) HandlePlayerLeft(pl routing.PlayerLeft) {
+	defer fmt.Println("------------------------")
+	fmt.Println()
+	fmt.Println("==== Player Left ====")
+	gs.mu.Lock()
+	delete(gs.others, pl.Username)
+	gs.mu.Unlock()
+	fmt.Printf("%s has left %s (%s). Their units have been removed.\n", pl.Username, pl.Game, pl.Reason)
+}
+
+// SeePlayers notes that the named players are in the game.
+func (gs *GameState) SeePlayers(usernames ...string) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	for _, name := range usernames {
+		if name != gs.Player.Username {
+			gs.others[name] = struct{}{}
+		}
+	}
+}
+
+// OtherPlayers lists the other players we know to be in the game.
+func (gs *GameState) OtherPlayers() []string {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	names := []string{}
+	for name := range gs.others {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func NewPlayerLeftEvent(game, username string) GameEvent {
+	return newEvent(EventPlayerLeft, game, username)
+}
@@ -0,0 +1,301 @@
+package gamelogic
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+// World is the state of every player in a game, rebuilt from its events.
+type World struct {
+	Game    string
+	Players map[string]*GameState
+	Scores  map[string]int
+	// Fielded holds the players who have had units on the map since the
+	// game started or was reset.
+	Fielded map[string]bool
+	Paused  bool
+	Over    *routing.GameOver
+	// Deadline is when the highest score wins, or zero if the game has no
+	// deadline.
+	Deadline time.Time
+	At       time.Time
+	// treaties are the treaties in force, by pair of players.
+	treaties map[string]routing.Treaty
+	// Seq is the Seq of the last event applied. Events stored without one
+	// count by their place in the log.
+	Seq int
+	mu  *sync.RWMutex
+}
+
+func NewWorld(game string) *World {
+	return &World{
+		Game:     game,
+		Players:  map[string]*GameState{},
+		Scores:   map[string]int{},
+		Fielded:  map[string]bool{},
+		treaties: map[string]routing.Treaty{},
+		mu:       &sync.RWMutex{},
+	}
+}
+
+// Replay rebuilds the world by applying events in order. Events after until
+// are ignored unless until is the zero time.
+func Replay(game string, events []GameEvent, until time.Time) *World {
+	w := NewWorld(game)
+	for _, ev := range events {
+		if !until.IsZero() && ev.Time.After(until) {
+			break
+		}
+		w.Apply(ev)
+	}
+	return w
+}
+
+// Player returns the rebuilt state of username, creating it if the player
+// hasn't been seen yet.
+func (w *World) Player(username string) *GameState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.player(username)
+}
+
+func (w *World) player(username string) *GameState {
+	gs, ok := w.Players[username]
+	if !ok {
+		gs = NewGameState(username)
+		if w.Paused {
+			gs.pauseGame()
+		}
+		w.Players[username] = gs
+	}
+	return gs
+}
+
+// UnitCount is how many units username has. Unlike Player it doesn't add
+// them to the world, so asking about someone who hasn't played is harmless.
+func (w *World) UnitCount(username string) int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	gs, ok := w.Players[username]
+	if !ok {
+		return 0
+	}
+	return len(gs.getUnitsSnap())
+}
+
+// HasFielded reports whether username has had units on the map.
+func (w *World) HasFielded(username string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.Fielded[username]
+}
+
+func (w *World) IsOver() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.Over != nil
+}
+
+// GameOver is how the game ended, or nil while it's still on.
+func (w *World) GameOver() *routing.GameOver {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.Over
+}
+
+func (w *World) VictoryDeadline() time.Time {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.Deadline
+}
+
+// Treaties are the treaties signed and not yet ended. Ceasefires that have
+// run out are still here until their end is recorded.
+func (w *World) Treaties() []routing.Treaty {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	treaties := []routing.Treaty{}
+	for _, t := range w.treaties {
+		treaties = append(treaties, t)
+	}
+	return treaties
+}
+
+// LastSeq is the Seq of the last event applied.
+func (w *World) LastSeq() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.Seq
+}
+
+func (w *World) IsPaused() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.Paused
+}
+
+// Usernames lists everyone who has played in the game.
+func (w *World) Usernames() []string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.usernames()
+}
+
+func (w *World) usernames() []string {
+	names := []string{}
+	for name := range w.Players {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (w *World) Apply(ev GameEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.At = ev.Time
+	if ev.Seq > 0 {
+		w.Seq = ev.Seq
+	} else {
+		w.Seq++
+	}
+	switch ev.Type {
+	case EventSpawn:
+		if ev.Unit == nil {
+			return
+		}
+		gs := w.player(ev.Username)
+		gs.addUnit(*ev.Unit)
+		gs.spend(RankCost(ev.Unit.Rank))
+		w.Fielded[ev.Username] = true
+	case EventMove:
+		if ev.Move == nil {
+			return
+		}
+		// only where the units are comes from the event: their rank and
+		// veterancy stay as the world has them
+		gs := w.player(ev.Username)
+		for _, moved := range ev.Move.Units {
+			unit, ok := gs.GetUnit(moved.ID)
+			if !ok {
+				continue
+			}
+			unit.Location = moved.Location
+			unit.Destination = moved.Destination
+			unit.Remaining = moved.Remaining
+			gs.UpdateUnit(unit)
+		}
+	case EventWarDeclared:
+		// declaring war doesn't change anything until the outcome is known
+	case EventWarOutcome:
+		if ev.Result == nil {
+			return
+		}
+		w.player(ev.Result.Loser).removeUnitsInLocation(ev.Result.Location)
+		if ev.Result.Draw {
+			w.player(ev.Result.Winner).removeUnitsInLocation(ev.Result.Location)
+			w.Scores[ev.Result.Winner] += WarDrawPoints
+			w.Scores[ev.Result.Loser] += WarDrawPoints
+		} else {
+			w.Scores[ev.Result.Winner] += WarWinPoints
+			w.player(ev.Result.Winner).gainVeterancy(ev.Result.Location)
+		}
+	case EventIncome:
+		for username, income := range ev.Income {
+			w.player(username).earn(income)
+		}
+	case EventPlayerLeft:
+		w.player(ev.Username).removeAllUnits()
+	case EventReset:
+		for _, gs := range w.Players {
+			gs.reset()
+		}
+		w.Scores = map[string]int{}
+		w.Fielded = map[string]bool{}
+		w.treaties = map[string]routing.Treaty{}
+		w.Over = nil
+	case EventGameOver:
+		w.Over = ev.GameOver
+	case EventTreatySigned:
+		if ev.Treaty != nil {
+			w.treaties[pairKey(ev.Treaty.Proposer, ev.Treaty.Partner)] = *ev.Treaty
+		}
+	case EventTreatyEnded:
+		if ev.Treaty != nil {
+			delete(w.treaties, pairKey(ev.Treaty.Proposer, ev.Treaty.Partner))
+		}
+	case EventDeadline:
+		if ev.Deadline == nil {
+			return
+		}
+		w.Deadline = *ev.Deadline
+	case EventPause:
+		w.Paused = true
+		for _, gs := range w.Players {
+			gs.pauseGame()
+		}
+	case EventResume:
+		w.Paused = false
+		for _, gs := range w.Players {
+			gs.resumeGame()
+		}
+	}
+}
+
+// CheckMove reports whether every unit in ev is one the player has. Events
+// that aren't moves always pass.
+func (w *World) CheckMove(ev GameEvent) error {
+	if ev.Type != EventMove || ev.Move == nil {
+		return nil
+	}
+	if ev.Move.Player.Username != ev.Username {
+		return fmt.Errorf("%s can't move %s's units", ev.Username, ev.Move.Player.Username)
+	}
+	w.mu.RLock()
+	gs, ok := w.Players[ev.Username]
+	w.mu.RUnlock()
+	for _, unit := range ev.Move.Units {
+		if !ok {
+			return fmt.Errorf("%s has no unit with ID %d", ev.Username, unit.ID)
+		}
+		if _, found := gs.GetUnit(unit.ID); !found {
+			return fmt.Errorf("%s has no unit with ID %d", ev.Username, unit.ID)
+		}
+	}
+	return nil
+}
+
+func (w *World) Print() {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	fmt.Printf("==== World of %s", w.Game)
+	if !w.At.IsZero() {
+		fmt.Printf(" as of %s", w.At.Format(time.RFC3339))
+	}
+	fmt.Println(" ====")
+	if w.Paused {
+		fmt.Println("The game is paused.")
+	} else {
+		fmt.Println("The game is not paused.")
+	}
+
+	for _, name := range w.usernames() {
+		gs := w.Players[name]
+		units := gs.getUnitsSnap()
+		sort.Slice(units, func(i, j int) bool { return units[i].ID < units[j].ID })
+		fmt.Printf("%s has %d units and a balance of %d.\n", name, len(units), gs.GetBalance())
+		for _, unit := range units {
+			fmt.Printf("* %v: %v, %v, veterancy %v\n", unit.ID, unit.Location, unit.Rank, unit.Veterancy)
+		}
+	}
+	if w.Over != nil {
+		fmt.Println("The game is over.")
+		printGameOver(os.Stdout, *w.Over)
+	}
+	fmt.Println("------------------------")
+}
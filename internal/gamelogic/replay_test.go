@@ -0,0 +1,382 @@
+package gamelogic
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+var start = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// timed gives events a second apart, so replaying until a time can stop
+// part way.
+func timed(events ...GameEvent) []GameEvent {
+	for i := range events {
+		events[i].Time = start.Add(time.Duration(i) * time.Second)
+	}
+	return events
+}
+
+func spawn(username string, id int, rank UnitRank, loc Location) GameEvent {
+	return NewSpawnEvent("test", username, Unit{ID: id, Rank: rank, Location: loc})
+}
+
+func move(username string, loc Location, units ...Unit) GameEvent {
+	for i := range units {
+		units[i].Location = loc
+	}
+	return NewMoveEvent("test", ArmyMove{
+		Player:     Player{Username: username},
+		Units:      units,
+		ToLocation: loc,
+		Arrival:    true,
+	})
+}
+
+// war is a war between attacker and defender in asia.
+func war(attacker, defender, winner, loser string, outcome WarOutcome) GameEvent {
+	rw := RecognitionOfWar{
+		Attacker: Player{Username: attacker, Units: map[int]Unit{1: {ID: 1, Location: "asia"}}},
+		Defender: Player{Username: defender, Units: map[int]Unit{1: {ID: 1, Location: "asia"}}},
+	}
+	return NewWarOutcomeEvent("test", attacker, rw, outcome, winner, loser)
+}
+
+// wantPlayer is what a player should look like after the replay. units maps
+// unit IDs to where they should be.
+type wantPlayer struct {
+	units   map[int]Location
+	balance int
+	score   int
+}
+
+func TestReplay(t *testing.T) {
+	infantry := Unit{ID: 1, Rank: RankInfantry}
+	tests := []struct {
+		name    string
+		events  []GameEvent
+		until   time.Time
+		players map[string]wantPlayer
+		paused  bool
+		over    bool
+	}{
+		{
+			name:   "spawn",
+			events: timed(spawn("alice", 1, RankInfantry, "europe")),
+			players: map[string]wantPlayer{
+				"alice": {units: map[int]Location{1: "europe"}, balance: StartingBalance - 2},
+			},
+		},
+		{
+			name: "spawn and move",
+			events: timed(
+				spawn("alice", 1, RankInfantry, "europe"),
+				spawn("alice", 2, RankCavalry, "europe"),
+				move("alice", "asia", infantry),
+			),
+			players: map[string]wantPlayer{
+				"alice": {units: map[int]Location{1: "asia", 2: "europe"}, balance: StartingBalance - 7},
+			},
+		},
+		{
+			name: "move with units the player doesn't have",
+			events: timed(
+				spawn("alice", 1, RankInfantry, "europe"),
+				move("alice", "asia", infantry, Unit{ID: 9, Rank: RankArtillery}),
+			),
+			players: map[string]wantPlayer{
+				"alice": {units: map[int]Location{1: "asia"}, balance: StartingBalance - 2},
+			},
+		},
+		{
+			name: "war won",
+			events: timed(
+				spawn("alice", 1, RankCavalry, "asia"),
+				spawn("bob", 1, RankInfantry, "europe"),
+				move("bob", "asia", infantry),
+				war("bob", "alice", "alice", "bob", WarOutcomeOpponentWon),
+			),
+			players: map[string]wantPlayer{
+				"alice": {units: map[int]Location{1: "asia"}, balance: StartingBalance - 5, score: WarWinPoints},
+				"bob":   {units: map[int]Location{}, balance: StartingBalance - 2},
+			},
+		},
+		{
+			name: "war drawn",
+			events: timed(
+				spawn("alice", 1, RankInfantry, "asia"),
+				spawn("alice", 2, RankInfantry, "europe"),
+				spawn("bob", 1, RankInfantry, "asia"),
+				war("bob", "alice", "bob", "alice", WarOutcomeDraw),
+			),
+			players: map[string]wantPlayer{
+				"alice": {units: map[int]Location{2: "europe"}, balance: StartingBalance - 4, score: WarDrawPoints},
+				"bob":   {units: map[int]Location{}, balance: StartingBalance - 2, score: WarDrawPoints},
+			},
+		},
+		{
+			name: "until",
+			events: timed(
+				spawn("alice", 1, RankInfantry, "europe"),
+				move("alice", "asia", infantry),
+			),
+			until: start,
+			players: map[string]wantPlayer{
+				"alice": {units: map[int]Location{1: "europe"}, balance: StartingBalance - 2},
+			},
+		},
+		{
+			name: "income, pause and player left",
+			events: timed(
+				spawn("alice", 1, RankInfantry, "europe"),
+				spawn("bob", 1, RankInfantry, "asia"),
+				NewIncomeEvent("test", map[string]int{"alice": 3}),
+				NewPauseEvent("test", true),
+				NewPlayerLeftEvent("test", "bob"),
+			),
+			players: map[string]wantPlayer{
+				"alice": {units: map[int]Location{1: "europe"}, balance: StartingBalance + 1},
+				"bob":   {units: map[int]Location{}, balance: StartingBalance - 2},
+			},
+			paused: true,
+		},
+		{
+			name: "reset and game over",
+			events: timed(
+				spawn("alice", 1, RankInfantry, "europe"),
+				war("bob", "alice", "alice", "bob", WarOutcomeOpponentWon),
+				NewResetEvent("test"),
+				spawn("alice", 1, RankCavalry, "africa"),
+				NewGameOverEvent("test", routing.GameOver{Winner: "alice", Reason: "time ran out"}),
+			),
+			players: map[string]wantPlayer{
+				"alice": {units: map[int]Location{1: "africa"}, balance: StartingBalance - 5},
+				"bob":   {units: map[int]Location{}, balance: StartingBalance},
+			},
+			over: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := Replay("test", tt.events, tt.until)
+			if got := w.Usernames(); len(got) != len(tt.players) {
+				t.Errorf("players = %v, want %d of them", got, len(tt.players))
+			}
+			for name, want := range tt.players {
+				gs := w.Player(name)
+				p := gs.GetPlayerSnap()
+				if len(p.Units) != len(want.units) {
+					t.Errorf("%s has units %v, want %v", name, p.Units, want.units)
+				}
+				for id, loc := range want.units {
+					if p.Units[id].Location != loc {
+						t.Errorf("%s's unit %d is in %q, want %q", name, id, p.Units[id].Location, loc)
+					}
+				}
+				if gs.GetBalance() != want.balance {
+					t.Errorf("%s's balance = %d, want %d", name, gs.GetBalance(), want.balance)
+				}
+				if w.Scores[name] != want.score {
+					t.Errorf("%s's score = %d, want %d", name, w.Scores[name], want.score)
+				}
+				if gs.IsPaused() != tt.paused {
+					t.Errorf("%s paused = %v, want %v", name, gs.IsPaused(), tt.paused)
+				}
+			}
+			if w.IsPaused() != tt.paused {
+				t.Errorf("paused = %v, want %v", w.IsPaused(), tt.paused)
+			}
+			if w.IsOver() != tt.over {
+				t.Errorf("over = %v, want %v", w.IsOver(), tt.over)
+			}
+		})
+	}
+}
+
+func TestReplayVeterancy(t *testing.T) {
+	events := timed(spawn("alice", 1, RankInfantry, "asia"))
+	for i := 0; i < PromotionVeterancy; i++ {
+		events = append(events, war("bob", "alice", "alice", "bob", WarOutcomeOpponentWon))
+	}
+	w := Replay("test", events, time.Time{})
+	unit, _ := w.Player("alice").GetUnit(1)
+	if unit.Rank != RankCavalry || unit.Veterancy != 0 {
+		t.Errorf("unit after %d wins = %+v, want promoted to cavalry", PromotionVeterancy, unit)
+	}
+	if w.Scores["alice"] != PromotionVeterancy*WarWinPoints {
+		t.Errorf("score = %d, want %d", w.Scores["alice"], PromotionVeterancy*WarWinPoints)
+	}
+}
+
+func TestReplayDeadline(t *testing.T) {
+	deadline := start.Add(time.Hour)
+	events := timed(
+		NewDeadlineEvent("test", deadline),
+		spawn("alice", 1, RankInfantry, "europe"),
+		NewResetEvent("test"),
+	)
+	w := Replay("test", events, time.Time{})
+	if !w.VictoryDeadline().Equal(deadline) {
+		t.Errorf("deadline = %v after a reset, want %v", w.VictoryDeadline(), deadline)
+	}
+}
+
+// useEventsDir stores events in a temporary directory for the test.
+func useEventsDir(t *testing.T) {
+	old := eventsDir
+	eventsDir = t.TempDir()
+	t.Cleanup(func() { eventsDir = old })
+}
+
+func TestLoadEvents(t *testing.T) {
+	useEventsDir(t)
+	stored := timed(
+		spawn("alice", 1, RankInfantry, "europe"),
+		move("alice", "asia", Unit{ID: 1, Rank: RankInfantry}),
+		NewPauseEvent("test", true),
+	)
+	for _, ev := range stored {
+		err := AppendEvent(ev)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := LoadEvents("test", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != len(stored) {
+		t.Fatalf("loaded %d events, want %d", len(events), len(stored))
+	}
+	for i, ev := range events {
+		if ev.Type != stored[i].Type || !ev.Time.Equal(stored[i].Time) {
+			t.Errorf("event %d = %s at %v, want %s at %v", i, ev.Type, ev.Time, stored[i].Type, stored[i].Time)
+		}
+	}
+	w := Replay("test", events, time.Time{})
+	if unit, _ := w.Player("alice").GetUnit(1); unit.Location != "asia" {
+		t.Errorf("alice's unit is in %q after the round trip, want asia", unit.Location)
+	}
+
+	events, err = LoadEvents("test", start.Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Errorf("loaded %d events until the move, want 2", len(events))
+	}
+
+	_, err = LoadEvents("missing", time.Time{})
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("loading a game without events: %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestLoadEventsDamaged(t *testing.T) {
+	good := `{"Type":"spawn","Time":"2024-05-01T12:00:00Z","Game":"test","Username":"alice","Unit":{"ID":1,"Rank":"infantry","Location":"europe"}}`
+	tests := []struct {
+		name    string
+		file    string
+		want    int
+		wantErr string
+	}{
+		{name: "truncated last line", file: good + "\n" + good[:40], want: 1},
+		{name: "last line without newline", file: good + "\n" + good, want: 2},
+		{name: "blank lines", file: "\n" + good + "\n\n", want: 1},
+		{name: "malformed line", file: good + "\nnot json\n" + good + "\n", wantErr: "line 2"},
+		{name: "truncated line in the middle", file: good[:40] + "\n" + good + "\n", wantErr: "line 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useEventsDir(t)
+			err := os.WriteFile(filepath.Join(eventsDir, "test"+eventsExt), []byte(tt.file), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			events, err := LoadEvents("test", time.Time{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadEvents() error = %v, want one about %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadEvents() error: %v", err)
+			}
+			if len(events) != tt.want {
+				t.Errorf("loaded %d events, want %d", len(events), tt.want)
+			}
+		})
+	}
+}
+
+func TestReplayMoveKeepsRank(t *testing.T) {
+	events := timed(
+		spawn("alice", 1, RankInfantry, "europe"),
+		move("alice", "asia", Unit{ID: 1, Rank: RankArtillery, Veterancy: 5}),
+	)
+	w := Replay("test", events, time.Time{})
+	unit, _ := w.Player("alice").GetUnit(1)
+	if unit.Location != "asia" || unit.Rank != RankInfantry || unit.Veterancy != 0 {
+		t.Errorf("unit after the move = %+v, want the same infantry in asia", unit)
+	}
+}
+
+func TestCheckMove(t *testing.T) {
+	w := Replay("test", timed(
+		spawn("alice", 1, RankInfantry, "europe"),
+		spawn("bob", 1, RankInfantry, "asia"),
+	), time.Time{})
+	stolen := move("bob", "asia", Unit{ID: 1})
+	stolen.Username = "alice"
+	tests := []struct {
+		name string
+		ev   GameEvent
+		ok   bool
+	}{
+		{name: "own unit", ev: move("alice", "asia", Unit{ID: 1}), ok: true},
+		{name: "unknown unit", ev: move("alice", "asia", Unit{ID: 1}, Unit{ID: 2})},
+		{name: "player who hasn't spawned", ev: move("carol", "asia", Unit{ID: 1})},
+		{name: "someone else's army", ev: stolen},
+		{name: "not a move", ev: spawn("carol", 1, RankInfantry, "asia"), ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := w.CheckMove(tt.ev)
+			if (err == nil) != tt.ok {
+				t.Errorf("CheckMove() = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+	if got := w.Usernames(); len(got) != 2 {
+		t.Errorf("players = %v after checking moves, want only alice and bob", got)
+	}
+}
+
+func TestReplayTreaties(t *testing.T) {
+	alliance := routing.Treaty{Kind: routing.TreatyAlliance, Proposer: "alice", Partner: "bob"}
+	ceasefire := routing.Treaty{Kind: routing.TreatyCeasefire, Proposer: "carol", Partner: "alice"}
+	tests := []struct {
+		name   string
+		events []GameEvent
+		want   int
+	}{
+		{"signed", timed(NewTreatyEvent("test", alliance, true), NewTreatyEvent("test", ceasefire, true)), 2},
+		{"ended", timed(NewTreatyEvent("test", alliance, true), NewTreatyEvent("test", alliance, false)), 0},
+		{"reset", timed(NewTreatyEvent("test", alliance, true), NewResetEvent("test")), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := Replay("test", tt.events, time.Time{})
+			if got := w.Treaties(); len(got) != tt.want {
+				t.Errorf("treaties = %+v, want %d", got, tt.want)
+			}
+		})
+	}
+}
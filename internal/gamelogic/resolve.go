@@ -0,0 +1,170 @@
+package gamelogic
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ResolveTurn carries out a turn's orders all at once, so nobody gains from
+// submitting first: every spawn, then every move, then a war wherever armies
+// not at peace meet. Players go in username order, and their orders in the
+// order they were queued. Each event is passed to record, which must apply
+// it to the world before the next one is worked out. The orders that
+// couldn't be carried out are returned by player, with the reason.
+func (w *World) ResolveTurn(orders map[string][][]string, atPeace func(a, b string) bool, record func(GameEvent) error) (map[string][]string, error) {
+	usernames := []string{}
+	for username := range orders {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+
+	rejected := map[string][]string{}
+	reject := func(username string, words []string, err error) {
+		rejected[username] = append(rejected[username], fmt.Sprintf("%s: %v", strings.Join(words, " "), err))
+	}
+
+	for _, username := range usernames {
+		for _, words := range orders[username] {
+			if len(words) == 0 {
+				continue
+			}
+			switch words[0] {
+			case "spawn":
+				ev, err := w.spawnOrder(username, words)
+				if err != nil {
+					reject(username, words, err)
+					continue
+				}
+				err = record(ev)
+				if err != nil {
+					return rejected, err
+				}
+			case "move":
+			default:
+				reject(username, words, errors.New("not an order"))
+			}
+		}
+	}
+
+	// arrived holds the territories each player moved into this turn. They
+	// are the attackers in the wars there.
+	arrived := map[string]map[Location]bool{}
+	for _, username := range usernames {
+		for _, words := range orders[username] {
+			if len(words) == 0 || words[0] != "move" {
+				continue
+			}
+			ev, err := w.moveOrder(username, words)
+			if err != nil {
+				reject(username, words, err)
+				continue
+			}
+			err = record(ev)
+			if err != nil {
+				return rejected, err
+			}
+			if arrived[username] == nil {
+				arrived[username] = map[Location]bool{}
+			}
+			arrived[username][ev.Move.ToLocation] = true
+		}
+	}
+
+	for _, loc := range Locations() {
+		for {
+			rw, ok := w.nextWar(loc, arrived, atPeace)
+			if !ok {
+				break
+			}
+			err := record(NewWarDeclaredEvent(w.Game, "", rw))
+			if err != nil {
+				return rejected, err
+			}
+			outcome := WarOutcomeOpponentWon
+			winner, loser, draw := FightWar(rw)
+			if draw {
+				outcome = WarOutcomeDraw
+			}
+			err = record(NewWarOutcomeEvent(w.Game, "", rw, outcome, winner, loser))
+			if err != nil {
+				return rejected, err
+			}
+		}
+	}
+	return rejected, nil
+}
+
+func (w *World) spawnOrder(username string, words []string) (GameEvent, error) {
+	loc, rank, err := parseSpawn(words)
+	if err != nil {
+		return GameEvent{}, err
+	}
+	gs := w.Player(username)
+	err = gs.canAfford(rank)
+	if err != nil {
+		return GameEvent{}, err
+	}
+	unit := Unit{
+		ID:       gs.nextUnitID(),
+		Rank:     rank,
+		Location: loc,
+	}
+	return NewSpawnEvent(w.Game, username, unit), nil
+}
+
+// moveOrder moves the units straight to where they're going: in a turn
+// they all arrive together.
+func (w *World) moveOrder(username string, words []string) (GameEvent, error) {
+	to, unitIDs, err := parseMove(words)
+	if err != nil {
+		return GameEvent{}, err
+	}
+	player := w.Player(username).GetPlayerSnap()
+	units := []Unit{}
+	for _, unitID := range unitIDs {
+		unit, ok := player.Units[unitID]
+		if !ok {
+			return GameEvent{}, fmt.Errorf("unit with ID %v not found", unitID)
+		}
+		unit.Location = to
+		units = append(units, unit)
+	}
+	for _, unit := range units {
+		player.Units[unit.ID] = unit
+	}
+	return NewMoveEvent(w.Game, ArmyMove{
+		Player:     player,
+		Units:      units,
+		ToLocation: to,
+		Arrival:    true,
+	}), nil
+}
+
+// nextWar finds the first two players with units in loc who aren't at peace.
+// The one who moved in this turn attacks; if both or neither did, the first
+// by username does. Only their units in loc are part of the war.
+func (w *World) nextWar(loc Location, arrived map[string]map[Location]bool, atPeace func(a, b string) bool) (RecognitionOfWar, bool) {
+	armies := map[string]Player{}
+	names := []string{}
+	for _, username := range w.Usernames() {
+		army := unitsIn(w.Player(username).GetPlayerSnap(), loc)
+		if len(army.Units) > 0 {
+			armies[username] = army
+			names = append(names, username)
+		}
+	}
+	for i, a := range names {
+		for _, b := range names[i+1:] {
+			if atPeace(a, b) {
+				continue
+			}
+			if arrived[b][loc] && !arrived[a][loc] {
+				a, b = b, a
+			}
+			return RecognitionOfWar{Attacker: armies[a], Defender: armies[b]}, true
+		}
+	}
+	return RecognitionOfWar{}, false
+}
@@ -0,0 +1,240 @@
+package gamelogic
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+// resolve runs a turn against a world where the starting events have
+// already happened, and returns the events it recorded.
+func resolve(t *testing.T, setup []GameEvent, orders map[string][][]string, atPeace bool) (*World, []GameEvent, map[string][]string) {
+	t.Helper()
+	w := Replay("test", setup, time.Time{})
+	events := []GameEvent{}
+	record := func(ev GameEvent) error {
+		w.Apply(ev)
+		events = append(events, ev)
+		return nil
+	}
+	peace := func(a, b string) bool { return atPeace }
+	rejected, err := w.ResolveTurn(orders, peace, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w, events, rejected
+}
+
+func order(words string) []string {
+	return strings.Fields(words)
+}
+
+func eventTypes(events []GameEvent) []EventType {
+	types := []EventType{}
+	for _, ev := range events {
+		types = append(types, ev.Type)
+	}
+	return types
+}
+
+func TestResolveTurnSpawnsBeforeMoves(t *testing.T) {
+	orders := map[string][][]string{
+		"bob":   {order("move asia 1"), order("spawn europe infantry")},
+		"alice": {order("spawn africa cavalry"), order("spawn africa cavalry"), order("spawn africa infantry")},
+	}
+	w, events, rejected := resolve(t, nil, orders, false)
+
+	want := []EventType{EventSpawn, EventSpawn, EventSpawn, EventMove}
+	if got := eventTypes(events); !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	if events[0].Username != "alice" || events[2].Username != "bob" {
+		t.Errorf("spawns by %s, %s, %s, want alice's before bob's", events[0].Username, events[1].Username, events[2].Username)
+	}
+	if unit, _ := w.Player("bob").GetUnit(1); unit.Location != "asia" {
+		t.Errorf("bob's new unit is in %q, want asia: it was spawned before the move", unit.Location)
+	}
+	if got := w.Balance("alice"); got != 0 {
+		t.Errorf("alice's balance = %d, want 0", got)
+	}
+	if len(rejected["alice"]) != 1 || !strings.HasPrefix(rejected["alice"][0], "spawn africa infantry:") {
+		t.Errorf("alice's rejected orders = %q, want the spawn she couldn't afford", rejected["alice"])
+	}
+	if len(rejected["bob"]) != 0 {
+		t.Errorf("bob's rejected orders = %q, want none", rejected["bob"])
+	}
+}
+
+func TestResolveTurnRejects(t *testing.T) {
+	orders := map[string][][]string{
+		"alice": {order("dance"), order("move asia 7"), order("spawn atlantis infantry")},
+	}
+	_, events, rejected := resolve(t, nil, orders, false)
+	if len(events) != 0 {
+		t.Errorf("events = %v, want none", eventTypes(events))
+	}
+	if len(rejected["alice"]) != 3 {
+		t.Errorf("rejected = %q, want all three orders", rejected["alice"])
+	}
+}
+
+func TestResolveTurnUnitIDs(t *testing.T) {
+	// unit 1 died, unit 2 is still about
+	setup := timed(
+		spawn("alice", 1, RankInfantry, "asia"),
+		spawn("alice", 2, RankInfantry, "europe"),
+		war("bob", "alice", "bob", "alice", WarOutcomeOpponentWon),
+	)
+	w, _, _ := resolve(t, setup, map[string][][]string{"alice": {order("spawn africa infantry")}}, false)
+	if unit, ok := w.Player("alice").GetUnit(3); !ok || unit.Location != "africa" {
+		t.Errorf("alice's units = %v, want the new one to be unit 3", w.Player("alice").GetPlayerSnap().Units)
+	}
+	if unit, _ := w.Player("alice").GetUnit(2); unit.Location != "europe" {
+		t.Errorf("unit 2 is in %q, want it left in europe", unit.Location)
+	}
+
+	// unit 2, the newest, dies: its ID still isn't reused
+	setup = timed(
+		spawn("alice", 1, RankInfantry, "europe"),
+		spawn("alice", 2, RankInfantry, "asia"),
+		war("bob", "alice", "bob", "alice", WarOutcomeOpponentWon),
+	)
+	w, _, _ = resolve(t, setup, map[string][][]string{"alice": {order("spawn africa infantry")}}, false)
+	if _, ok := w.Player("alice").GetUnit(3); !ok {
+		t.Errorf("alice's units = %v, want the new one to be unit 3", w.Player("alice").GetPlayerSnap().Units)
+	}
+}
+
+func TestResolveTurnWars(t *testing.T) {
+	setup := timed(
+		spawn("alice", 1, RankInfantry, "europe"),
+		spawn("alice", 2, RankInfantry, "africa"),
+		spawn("bob", 1, RankCavalry, "asia"),
+		spawn("carol", 1, RankInfantry, "australia"),
+	)
+	tests := []struct {
+		name    string
+		orders  map[string][][]string
+		peace   bool
+		winner  string
+		loser   string
+		draw    bool
+		noWar   bool
+		attacks string
+	}{
+		{
+			name:    "mover attacks and wins",
+			orders:  map[string][][]string{"bob": {order("move europe 1")}},
+			winner:  "bob",
+			loser:   "alice",
+			attacks: "bob",
+		},
+		{
+			name:    "both move in, draw",
+			orders:  map[string][][]string{"alice": {order("move americas 2")}, "carol": {order("move americas 1")}},
+			winner:  "alice",
+			loser:   "carol",
+			draw:    true,
+			attacks: "alice",
+		},
+		{
+			name:   "at peace",
+			orders: map[string][][]string{"bob": {order("move europe 1")}},
+			peace:  true,
+			noWar:  true,
+		},
+		{
+			name:   "passing each other",
+			orders: map[string][][]string{"alice": {order("move asia 1")}, "bob": {order("move europe 1")}},
+			noWar:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, events, _ := resolve(t, setup, tt.orders, tt.peace)
+			var declared, outcome *GameEvent
+			for i := range events {
+				switch events[i].Type {
+				case EventWarDeclared:
+					declared = &events[i]
+				case EventWarOutcome:
+					outcome = &events[i]
+				}
+			}
+			if tt.noWar {
+				if declared != nil || outcome != nil {
+					t.Fatalf("events = %v, want no war", eventTypes(events))
+				}
+				return
+			}
+			if declared == nil || outcome == nil {
+				t.Fatalf("events = %v, want a war", eventTypes(events))
+			}
+			if declared.War.Attacker.Username != tt.attacks {
+				t.Errorf("attacker = %s, want %s", declared.War.Attacker.Username, tt.attacks)
+			}
+			r := outcome.Result
+			if r.Winner != tt.winner || r.Loser != tt.loser || r.Draw != tt.draw {
+				t.Errorf("result = %+v, want %s beating %s, draw %v", *r, tt.winner, tt.loser, tt.draw)
+			}
+			for _, unit := range w.Player(tt.loser).GetPlayerSnap().Units {
+				if unit.Location == r.Location {
+					t.Errorf("%s still has %+v in %s after losing there", tt.loser, unit, r.Location)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveTurnDeterministic(t *testing.T) {
+	setup := timed(
+		spawn("alice", 1, RankCavalry, "europe"),
+		spawn("bob", 1, RankCavalry, "asia"),
+		spawn("carol", 1, RankInfantry, "africa"),
+	)
+	orders := map[string][][]string{
+		"carol": {order("spawn europe infantry"), order("move europe 1")},
+		"bob":   {order("move europe 1"), order("spawn asia infantry")},
+		"alice": {order("spawn europe infantry")},
+	}
+	_, first, _ := resolve(t, setup, orders, false)
+	for i := 0; i < 10; i++ {
+		_, again, _ := resolve(t, setup, orders, false)
+		if len(again) != len(first) {
+			t.Fatalf("run %d recorded %v, the first %v", i, eventTypes(again), eventTypes(first))
+		}
+		for j := range again {
+			a, b := again[j], first[j]
+			a.Time = b.Time
+			if !reflect.DeepEqual(a, b) {
+				t.Fatalf("run %d event %d = %+v, the first run's %+v", i, j, a, b)
+			}
+		}
+	}
+}
+
+func TestHandleTurnResolved(t *testing.T) {
+	orders := map[string][][]string{
+		"alice": {order("spawn europe infantry"), order("move asia 1"), order("spawn europe artillery")},
+	}
+	_, events, rejected := resolve(t, nil, orders, false)
+
+	gs := NewGameState("alice")
+	gs.HandleTurnStarted(routing.TurnStarted{Number: 1, Deadline: start})
+	gs.HandleTurnResolved(TurnResolved{
+		Number:    1,
+		Submitted: map[string]int{"alice": 3},
+		Events:    events,
+		Rejected:  rejected["alice"],
+	})
+	p := gs.GetPlayerSnap()
+	if len(p.Units) != 1 || p.Units[1].Location != "asia" {
+		t.Errorf("alice's units = %v, want unit 1 in asia", p.Units)
+	}
+	if !gs.IsTurnBased() {
+		t.Error("turn-based mode ended before the final turn")
+	}
+}
@@ -5,30 +5,44 @@ import (
 	"fmt"
 )
 
-func (gs *GameState) CommandSpawn(words []string) error {
+func (gs *GameState) CommandSpawn(words []string) (Unit, error) {
+	locationName, rank, err := parseSpawn(words)
+	if err != nil {
+		return Unit{}, err
+	}
+	err = gs.canAfford(rank)
+	if err != nil {
+		return Unit{}, err
+	}
+
+	id := gs.nextUnitID()
+	unit := Unit{
+		ID:       id,
+		Rank:     rank,
+		Location: locationName,
+	}
+	gs.addUnit(unit)
+	gs.spend(RankCost(rank))
+
+	fmt.Printf("Spawned a(n) %s in %s with id %v for %d\n", rank, locationName, id, RankCost(rank))
+	return unit, nil
+}
+
+func parseSpawn(words []string) (Location, UnitRank, error) {
 	if len(words) < 3 {
-		return errors.New("usage: spawn <location> <rank>")
+		return "", "", errors.New("usage: spawn <location> <rank>")
 	}
 
 	locationName := words[1]
 	locations := getAllLocations()
 	if _, ok := locations[Location(locationName)]; !ok {
-		return fmt.Errorf("error: %s is not a valid location", locationName)
+		return "", "", fmt.Errorf("error: %s is not a valid location", locationName)
 	}
 
 	rank := words[2]
 	units := getAllRanks()
 	if _, ok := units[UnitRank(rank)]; !ok {
-		return fmt.Errorf("error: %s is not a valid unit", rank)
+		return "", "", fmt.Errorf("error: %s is not a valid unit", rank)
 	}
-
-	id := len(gs.getUnitsSnap()) + 1
-	gs.addUnit(Unit{
-		ID:       id,
-		Rank:     UnitRank(rank),
-		Location: Location(locationName),
-	})
-
-	fmt.Printf("Spawned a(n) %s in %s with id %v\n", rank, locationName, id)
-	return nil
+	return Location(locationName), UnitRank(rank), nil
 }
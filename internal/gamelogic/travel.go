@@ -0,0 +1,91 @@
+package gamelogic
+
+import (
+	"sort"
+	"time"
+)
+
+// TransitTick is how often units in transit move along.
+const TransitTick = time.Second
+
+// TimePerBorder is how long a unit of each rank takes to cross into the next
+// territory.
+func TimePerBorder(rank UnitRank) time.Duration {
+	switch rank {
+	case RankCavalry:
+		return 3 * time.Second
+	case RankInfantry:
+		return 6 * time.Second
+	case RankArtillery:
+		return 10 * time.Second
+	}
+	return 0
+}
+
+// getDistance is the number of borders between two territories.
+func getDistance(from, to Location) int {
+	dist := map[Location]int{from: 0}
+	queue := []Location{from}
+	for len(queue) > 0 {
+		loc := queue[0]
+		queue = queue[1:]
+		if loc == to {
+			return dist[loc]
+		}
+		for _, next := range AdjacentLocations(loc) {
+			if _, ok := dist[next]; !ok {
+				dist[next] = dist[loc] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return 0
+}
+
+func TravelTime(rank UnitRank, from, to Location) time.Duration {
+	return time.Duration(getDistance(from, to)) * TimePerBorder(rank)
+}
+
+func (u Unit) InTransit() bool {
+	return u.Destination != ""
+}
+
+// AdvanceTransit moves units in transit along by elapsed and lands the ones
+// that have arrived, returning one arrival per destination. Nothing moves
+// while the game is paused.
+func (gs *GameState) AdvanceTransit(elapsed time.Duration) []ArmyMove {
+	gs.mu.Lock()
+	if gs.Paused {
+		gs.mu.Unlock()
+		return nil
+	}
+	landed := map[Location][]Unit{}
+	for id, unit := range gs.Player.Units {
+		if !unit.InTransit() {
+			continue
+		}
+		unit.Remaining -= elapsed
+		if unit.Remaining <= 0 {
+			unit.Location = unit.Destination
+			unit.Destination = ""
+			unit.Remaining = 0
+			landed[unit.Location] = append(landed[unit.Location], unit)
+		}
+		gs.Player.Units[id] = unit
+	}
+	gs.mu.Unlock()
+
+	arrivals := []ArmyMove{}
+	player := gs.GetPlayerSnap()
+	for loc, units := range landed {
+		sort.Slice(units, func(i, j int) bool { return units[i].ID < units[j].ID })
+		arrivals = append(arrivals, ArmyMove{
+			Player:     player,
+			Units:      units,
+			ToLocation: loc,
+			Arrival:    true,
+		})
+	}
+	sort.Slice(arrivals, func(i, j int) bool { return arrivals[i].ToLocation < arrivals[j].ToLocation })
+	return arrivals
+}
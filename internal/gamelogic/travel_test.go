@@ -0,0 +1,73 @@
+package gamelogic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTravelTime(t *testing.T) {
+	tests := []struct {
+		rank     UnitRank
+		from, to Location
+		want     time.Duration
+	}{
+		{RankInfantry, "europe", "europe", 0},
+		{RankInfantry, "americas", "europe", 6 * time.Second},
+		{RankCavalry, "americas", "africa", 6 * time.Second},
+		{RankArtillery, "australia", "europe", 20 * time.Second},
+		{RankCavalry, "europe", "antarctica", 6 * time.Second},
+	}
+	for _, tt := range tests {
+		got := TravelTime(tt.rank, tt.from, tt.to)
+		if got != tt.want {
+			t.Errorf("TravelTime(%s, %s, %s) = %v, want %v", tt.rank, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestAdvanceTransit(t *testing.T) {
+	gs := NewGameState("mover")
+	gs.addUnit(Unit{ID: 1, Rank: RankCavalry, Location: "americas", Destination: "europe", Remaining: 3 * time.Second})
+	gs.addUnit(Unit{ID: 2, Rank: RankInfantry, Location: "americas", Destination: "europe", Remaining: 6 * time.Second})
+	gs.addUnit(Unit{ID: 3, Rank: RankInfantry, Location: "asia"})
+
+	arrivals := gs.AdvanceTransit(2 * time.Second)
+	if len(arrivals) != 0 {
+		t.Fatalf("got %d arrivals after a partial tick, want none", len(arrivals))
+	}
+	if u, _ := gs.GetUnit(1); u.Remaining != time.Second || u.Location != "americas" {
+		t.Errorf("unit 1 after 2s = %+v, want 1s left in americas", u)
+	}
+
+	arrivals = gs.AdvanceTransit(2 * time.Second)
+	if len(arrivals) != 1 {
+		t.Fatalf("got %d arrivals, want 1", len(arrivals))
+	}
+	arrival := arrivals[0]
+	if !arrival.Arrival || arrival.ToLocation != "europe" || len(arrival.Units) != 1 || arrival.Units[0].ID != 1 {
+		t.Errorf("arrival = %+v, want unit 1 arriving in europe", arrival)
+	}
+	u, _ := gs.GetUnit(1)
+	if u.Location != "europe" || u.InTransit() || u.Remaining != 0 {
+		t.Errorf("unit 1 after landing = %+v, want settled in europe", u)
+	}
+	if u, _ := gs.GetUnit(2); u.Location != "americas" || u.Remaining != 2*time.Second {
+		t.Errorf("unit 2 = %+v, want 2s left in americas", u)
+	}
+	if u, _ := gs.GetUnit(3); u.Location != "asia" || u.InTransit() {
+		t.Errorf("unit 3 = %+v, want untouched in asia", u)
+	}
+}
+
+func TestAdvanceTransitPaused(t *testing.T) {
+	gs := NewGameState("mover")
+	gs.addUnit(Unit{ID: 1, Rank: RankCavalry, Location: "americas", Destination: "europe", Remaining: time.Second})
+	gs.pauseGame()
+
+	if arrivals := gs.AdvanceTransit(time.Minute); arrivals != nil {
+		t.Errorf("got arrivals while paused: %+v", arrivals)
+	}
+	if u, _ := gs.GetUnit(1); u.Remaining != time.Second {
+		t.Errorf("unit moved while paused: %+v", u)
+	}
+}
@@ -0,0 +1,162 @@
+package gamelogic
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+type turnState struct {
+	enabled   bool
+	number    int
+	deadline  time.Time
+	submitted bool
+	orders    [][]string
+}
+
+func (gs *GameState) HandleTurnStarted(ts routing.TurnStarted) {
+	defer fmt.Println("------------------------")
+	fmt.Println()
+	gs.mu.Lock()
+	if !gs.turn.enabled || gs.turn.number != ts.Number {
+		gs.turn.orders = nil
+		gs.turn.submitted = false
+	}
+	gs.turn.enabled = true
+	gs.turn.number = ts.Number
+	gs.turn.deadline = ts.Deadline
+	gs.mu.Unlock()
+
+	fmt.Printf("==== Turn %d Started ====\n", ts.Number)
+	fmt.Printf("Submit your orders before %s (%v left).\n", ts.Deadline.Format(time.TimeOnly), time.Until(ts.Deadline).Round(time.Second))
+}
+
+// TurnResolved tells a player what the server made of a turn. Events are
+// the player's own spawns and moves, in the order the server carried them
+// out; everyone else hears about the moves and wars as they happen. Final is
+// set when the server leaves turn-based mode after this turn.
+type TurnResolved struct {
+	Number int
+	// Submitted is how many orders each player submitted.
+	Submitted map[string]int
+	Events    []GameEvent
+	// Rejected are the player's orders that couldn't be carried out, with
+	// the reason.
+	Rejected []string
+	Final    bool
+}
+
+// HandleTurnResolved applies the server's results for this player. The
+// balance comes separately, in an economy update.
+func (gs *GameState) HandleTurnResolved(tr TurnResolved) {
+	defer fmt.Println("------------------------")
+	fmt.Println()
+	fmt.Printf("==== Turn %d Resolved ====\n", tr.Number)
+	usernames := []string{}
+	for username := range tr.Submitted {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+	for _, username := range usernames {
+		fmt.Printf("%s submitted %d order(s)\n", username, tr.Submitted[username])
+	}
+	for _, ev := range tr.Events {
+		switch {
+		case ev.Type == EventSpawn && ev.Unit != nil:
+			gs.addUnit(*ev.Unit)
+			fmt.Printf("Spawned a(n) %s in %s with id %v\n", ev.Unit.Rank, ev.Unit.Location, ev.Unit.ID)
+		case ev.Type == EventMove && ev.Move != nil:
+			for _, unit := range ev.Move.Units {
+				gs.UpdateUnit(unit)
+			}
+			fmt.Printf("Moved %v unit(s) into %s\n", len(ev.Move.Units), ev.Move.ToLocation)
+		}
+	}
+	for _, reason := range tr.Rejected {
+		fmt.Printf("Not carried out: %s\n", reason)
+	}
+
+	gs.mu.Lock()
+	gs.turn.orders = nil
+	gs.turn.submitted = false
+	if tr.Final {
+		gs.turn.enabled = false
+	}
+	gs.mu.Unlock()
+
+	if tr.Final {
+		fmt.Println("Turn-based mode has ended.")
+	}
+}
+
+func (gs *GameState) IsTurnBased() bool {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.turn.enabled
+}
+
+// QueueOrder checks a spawn or move command and holds it until the end of the
+// current turn.
+func (gs *GameState) QueueOrder(words []string) error {
+	if len(words) == 0 {
+		return errors.New("no order given")
+	}
+	var err error
+	switch words[0] {
+	case "spawn":
+		_, _, err = parseSpawn(words)
+	case "move":
+		_, _, err = parseMove(words)
+	default:
+		err = fmt.Errorf("%s can't be queued as an order", words[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if gs.turn.submitted {
+		return fmt.Errorf("you already submitted your orders for turn %d", gs.turn.number)
+	}
+	gs.turn.orders = append(gs.turn.orders, words)
+	fmt.Printf("Queued order %d for turn %d\n", len(gs.turn.orders), gs.turn.number)
+	return nil
+}
+
+func (gs *GameState) CommandOrders() {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	if !gs.turn.enabled {
+		fmt.Println("The game is not turn-based.")
+		return
+	}
+	fmt.Printf("Turn %d, %v left.\n", gs.turn.number, time.Until(gs.turn.deadline).Round(time.Second))
+	if gs.turn.submitted {
+		fmt.Println("Your orders have been submitted.")
+	}
+	for i, order := range gs.turn.orders {
+		fmt.Printf("%d: %v\n", i+1, order)
+	}
+}
+
+func (gs *GameState) CommandSubmit() (routing.TurnOrders, error) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if !gs.turn.enabled {
+		return routing.TurnOrders{}, errors.New("the game is not turn-based")
+	}
+	if gs.turn.submitted {
+		return routing.TurnOrders{}, fmt.Errorf("you already submitted your orders for turn %d", gs.turn.number)
+	}
+	gs.turn.submitted = true
+	fmt.Printf("Submitting %d order(s) for turn %d\n", len(gs.turn.orders), gs.turn.number)
+	return routing.TurnOrders{
+		Number:   gs.turn.number,
+		Username: gs.Player.Username,
+		Orders:   gs.turn.orders,
+	}, nil
+}
@@ -0,0 +1,75 @@
+package gamelogic
+
+import "fmt"
+
+const (
+	// VeterancyPerWin is gained by every unit that survives a winning war.
+	VeterancyPerWin = 1
+	// PromotionVeterancy is the veterancy needed to be promoted to the next
+	// rank. Promoted units start again from zero.
+	PromotionVeterancy = 3
+)
+
+func getNextRank(rank UnitRank) (UnitRank, bool) {
+	switch rank {
+	case RankInfantry:
+		return RankCavalry, true
+	case RankCavalry:
+		return RankArtillery, true
+	}
+	return "", false
+}
+
+// gainVeterancy rewards the units in loc for winning a war there and returns
+// the ones that were promoted.
+func (gs *GameState) gainVeterancy(loc Location) []Unit {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	promoted := []Unit{}
+	for id, unit := range gs.Player.Units {
+		if unit.Location != loc {
+			continue
+		}
+		unit.Veterancy += VeterancyPerWin
+		if next, ok := getNextRank(unit.Rank); ok && unit.Veterancy >= PromotionVeterancy {
+			unit.Rank = next
+			unit.Veterancy = 0
+			promoted = append(promoted, unit)
+		}
+		gs.Player.Units[id] = unit
+	}
+	return promoted
+}
+
+// HandleWarResult brings the player's units in line with a war resolved by
+// another client, or by themselves. The winner's units gain veterancy; the
+// promoted ones are returned so they can be announced.
+func (gs *GameState) HandleWarResult(ev GameEvent) []Unit {
+	defer fmt.Println("------------------------")
+	result := ev.Result
+	username := gs.GetUsername()
+	fmt.Println()
+	fmt.Println("==== War Result ====")
+	if result.Draw {
+		fmt.Printf("The war between %s and %s in %s ended in a draw.\n", result.Winner, result.Loser, result.Location)
+	} else {
+		fmt.Printf("%s beat %s in %s.\n", result.Winner, result.Loser, result.Location)
+	}
+
+	lost := result.Loser == username || (result.Draw && result.Winner == username)
+	if lost && ev.Username != username {
+		// the client that resolved the war has already removed its own units
+		gs.removeUnitsInLocation(result.Location)
+		fmt.Printf("Your units in %s have been killed.\n", result.Location)
+	}
+	if result.Draw || result.Winner != username {
+		return nil
+	}
+
+	fmt.Printf("Your units in %s gained veterancy.\n", result.Location)
+	promoted := gs.gainVeterancy(result.Location)
+	for _, unit := range promoted {
+		fmt.Printf("Unit %v was promoted to %s!\n", unit.ID, unit.Rank)
+	}
+	return promoted
+}
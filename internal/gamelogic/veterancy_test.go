@@ -0,0 +1,77 @@
+package gamelogic
+
+import "testing"
+
+func TestHandleWarResult(t *testing.T) {
+	result := func(winner, loser string, draw bool) GameEvent {
+		rw := RecognitionOfWar{
+			Attacker: Player{Username: winner, Units: map[int]Unit{1: {ID: 1, Location: "asia"}}},
+			Defender: Player{Username: loser, Units: map[int]Unit{1: {ID: 1, Location: "asia"}}},
+		}
+		outcome := WarOutcomeOpponentWon
+		if draw {
+			outcome = WarOutcomeDraw
+		}
+		// bob's client resolved the war
+		return NewWarOutcomeEvent("test", "bob", rw, outcome, winner, loser)
+	}
+	tests := []struct {
+		name string
+		// veterancy is what alice's unit in asia starts with
+		veterancy int
+		ev        GameEvent
+		// want is alice's unit in asia afterwards, nil if it's dead
+		want     *Unit
+		promoted int
+	}{
+		{
+			name: "won",
+			ev:   result("alice", "bob", false),
+			want: &Unit{Rank: RankInfantry, Veterancy: 1},
+		},
+		{
+			name:      "won and promoted",
+			veterancy: PromotionVeterancy - 1,
+			ev:        result("alice", "bob", false),
+			want:      &Unit{Rank: RankCavalry, Veterancy: 0},
+			promoted:  1,
+		},
+		{
+			name: "lost",
+			ev:   result("bob", "alice", false),
+		},
+		{
+			name: "draw",
+			ev:   result("alice", "bob", true),
+		},
+		{
+			name: "someone else's war",
+			ev:   result("bob", "carol", false),
+			want: &Unit{Rank: RankInfantry},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := NewGameState("alice")
+			gs.addUnit(Unit{ID: 1, Rank: RankInfantry, Location: "asia", Veterancy: tt.veterancy})
+			gs.addUnit(Unit{ID: 2, Rank: RankInfantry, Location: "europe"})
+
+			promoted := gs.HandleWarResult(tt.ev)
+			if len(promoted) != tt.promoted {
+				t.Errorf("promoted %v, want %d unit(s)", promoted, tt.promoted)
+			}
+			unit, ok := gs.GetUnit(1)
+			switch {
+			case tt.want == nil && ok:
+				t.Errorf("unit in asia = %+v, want it dead", unit)
+			case tt.want != nil && !ok:
+				t.Error("unit in asia is dead, want it alive")
+			case tt.want != nil && (unit.Rank != tt.want.Rank || unit.Veterancy != tt.want.Veterancy):
+				t.Errorf("unit in asia = %+v, want %s with veterancy %d", unit, tt.want.Rank, tt.want.Veterancy)
+			}
+			if unit, _ := gs.GetUnit(2); unit.Veterancy != 0 {
+				t.Errorf("unit in europe gained veterancy %d from a war in asia", unit.Veterancy)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package gamelogic
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+const (
+	WarWinPoints  = 3
+	WarDrawPoints = 1
+)
+
+// VictoryConditions decide when a game ends. Any condition that is met ends
+// the game; zero values disable a condition.
+type VictoryConditions struct {
+	// HoldTerritories and HoldTicks: a player wins by holding at least
+	// HoldTerritories territories for HoldTicks ticks in a row.
+	HoldTerritories int
+	HoldTicks       int
+	// Elimination: a player wins when they're the last one with units.
+	Elimination bool
+	// Deadline: the highest score wins once it passes.
+	Deadline time.Time
+}
+
+func (vc VictoryConditions) String() string {
+	conditions := []string{}
+	if vc.HoldTerritories > 0 && vc.HoldTicks > 0 {
+		conditions = append(conditions, fmt.Sprintf("hold %d territories for %d ticks", vc.HoldTerritories, vc.HoldTicks))
+	}
+	if vc.Elimination {
+		conditions = append(conditions, "eliminate all opponents")
+	}
+	if !vc.Deadline.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("highest score at %s", vc.Deadline.Format(time.RFC3339)))
+	}
+	if len(conditions) == 0 {
+		return "none"
+	}
+	return strings.Join(conditions, ", ")
+}
+
+// VictoryTracker evaluates victory conditions once per tick and remembers
+// how long each player has held their territories.
+type VictoryTracker struct {
+	Conditions VictoryConditions
+	holding    map[string]int
+}
+
+func NewVictoryTracker(vc VictoryConditions) *VictoryTracker {
+	return &VictoryTracker{
+		Conditions: vc,
+		holding:    map[string]int{},
+	}
+}
+
+// Reset forgets how long anyone has held their territories.
+func (vt *VictoryTracker) Reset() {
+	vt.holding = map[string]int{}
+}
+
+func (vt *VictoryTracker) Evaluate(w *World, now time.Time) (routing.GameOver, bool) {
+	standings := w.Standings()
+	vc := vt.Conditions
+
+	if vc.HoldTerritories > 0 && vc.HoldTicks > 0 {
+		for _, s := range standings {
+			if s.Territories >= vc.HoldTerritories {
+				vt.holding[s.Username]++
+			} else {
+				vt.holding[s.Username] = 0
+			}
+		}
+		for _, s := range standings {
+			if vt.holding[s.Username] >= vc.HoldTicks {
+				reason := fmt.Sprintf("%s held %d territories for %d ticks", s.Username, vc.HoldTerritories, vc.HoldTicks)
+				return newGameOver(s.Username, reason, standings), true
+			}
+		}
+	}
+
+	if vc.Elimination {
+		// only players who have fielded units can be eliminated, so joining
+		// without spawning doesn't hand the game to the first to spawn
+		contenders := 0
+		survivors := []string{}
+		for _, s := range standings {
+			if !w.HasFielded(s.Username) {
+				continue
+			}
+			contenders++
+			if s.Units > 0 {
+				survivors = append(survivors, s.Username)
+			}
+		}
+		if contenders > 1 && len(survivors) == 1 {
+			reason := fmt.Sprintf("%s eliminated all opponents", survivors[0])
+			return newGameOver(survivors[0], reason, standings), true
+		}
+	}
+
+	if !vc.Deadline.IsZero() && !now.Before(vc.Deadline) {
+		winner := ""
+		if len(standings) > 0 && (len(standings) == 1 || standings[0].Score > standings[1].Score) {
+			winner = standings[0].Username
+		}
+		reason := "the deadline passed"
+		if winner == "" {
+			reason += " with no clear winner"
+		} else {
+			reason += fmt.Sprintf(" and %s had the highest score", winner)
+		}
+		return newGameOver(winner, reason, standings), true
+	}
+	return routing.GameOver{}, false
+}
+
+func newGameOver(winner, reason string, standings []routing.Standing) routing.GameOver {
+	return routing.GameOver{
+		Winner:    winner,
+		Reason:    reason,
+		Standings: standings,
+		Time:      time.Now(),
+	}
+}
+
+// NewDeadlineEvent records when the game's deadline passes, so it stays the
+// same when the server restarts.
+func NewDeadlineEvent(game string, deadline time.Time) GameEvent {
+	ev := newEvent(EventDeadline, game, "")
+	ev.Deadline = &deadline
+	return ev
+}
+
+func NewGameOverEvent(game string, over routing.GameOver) GameEvent {
+	ev := newEvent(EventGameOver, game, over.Winner)
+	ev.GameOver = &over
+	return ev
+}
+
+// Standings ranks every player by score: points from wars plus one point for
+// every territory they hold.
+func (w *World) Standings() []routing.Standing {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	standings := []routing.Standing{}
+	for _, name := range w.usernames() {
+		units := w.Players[name].getUnitsSnap()
+		territories := map[Location]struct{}{}
+		for _, unit := range units {
+			territories[unit.Location] = struct{}{}
+		}
+		standings = append(standings, routing.Standing{
+			Username:    name,
+			Score:       w.Scores[name] + len(territories),
+			Units:       len(units),
+			Territories: len(territories),
+		})
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		return standings[i].Score > standings[j].Score
+	})
+	return standings
+}
+
+func PrintStandings(standings []routing.Standing) {
+	writeStandings(os.Stdout, standings)
+}
+
+func writeStandings(w io.Writer, standings []routing.Standing) {
+	for i, s := range standings {
+		fmt.Fprintf(w, "%d. %s: %d points, %d units in %d territories\n", i+1, s.Username, s.Score, s.Units, s.Territories)
+	}
+}
+
+func (gs *GameState) HandleGameOver(over routing.GameOver) {
+	defer fmt.Println("------------------------")
+	fmt.Println()
+	fmt.Println("==== Game Over ====")
+	gs.mu.Lock()
+	gs.gameOver = &over
+	gs.mu.Unlock()
+	printGameOver(os.Stdout, over)
+	fmt.Println("The game is now read-only. You can still use status, help and quit.")
+}
+
+func (gs *GameState) IsGameOver() bool {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.gameOver != nil
+}
+
+// GameOver is how the game ended, or nil while it's still on.
+func (gs *GameState) GameOver() *routing.GameOver {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.gameOver
+}
+
+func printGameOver(w io.Writer, over routing.GameOver) {
+	if over.Winner != "" {
+		fmt.Fprintf(w, "%s has won the game!\n", over.Winner)
+	}
+	fmt.Fprintf(w, "The game ended because %s.\n", over.Reason)
+	fmt.Fprintln(w, "Final standings:")
+	writeStandings(w, over.Standings)
+}
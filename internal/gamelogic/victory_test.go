@@ -0,0 +1,71 @@
+package gamelogic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvaluateElimination(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []GameEvent
+		// joined have joined without spawning anything
+		joined []string
+		winner string
+		over   bool
+	}{
+		{
+			name:   "one player",
+			events: timed(spawn("alice", 1, RankInfantry, "europe")),
+		},
+		{
+			name:   "second player hasn't spawned",
+			events: timed(spawn("alice", 1, RankInfantry, "europe")),
+			joined: []string{"bob"},
+		},
+		{
+			name: "opponent wiped out",
+			events: timed(
+				spawn("alice", 1, RankCavalry, "asia"),
+				spawn("bob", 1, RankInfantry, "asia"),
+				war("bob", "alice", "alice", "bob", WarOutcomeOpponentWon),
+			),
+			joined: []string{"carol"},
+			winner: "alice",
+			over:   true,
+		},
+		{
+			name: "both still fielding units",
+			events: timed(
+				spawn("alice", 1, RankInfantry, "europe"),
+				spawn("bob", 1, RankInfantry, "asia"),
+			),
+		},
+		{
+			name: "wiped out before a reset",
+			events: timed(
+				spawn("alice", 1, RankCavalry, "asia"),
+				spawn("bob", 1, RankInfantry, "asia"),
+				war("bob", "alice", "alice", "bob", WarOutcomeOpponentWon),
+				NewResetEvent("test"),
+				spawn("alice", 1, RankInfantry, "europe"),
+			),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := Replay("test", tt.events, time.Time{})
+			for _, name := range tt.joined {
+				w.Player(name)
+			}
+			vt := NewVictoryTracker(VictoryConditions{Elimination: true})
+			over, ok := vt.Evaluate(w, start)
+			if ok != tt.over {
+				t.Fatalf("game over = %v (%+v), want %v", ok, over, tt.over)
+			}
+			if over.Winner != tt.winner {
+				t.Errorf("winner = %q, want %q", over.Winner, tt.winner)
+			}
+		})
+	}
+}
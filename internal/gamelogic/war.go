@@ -38,59 +38,84 @@ func (gs *GameState) HandleWar(rw RecognitionOfWar) (outcome WarOutcome, winner
 		return WarOutcomeNoUnits, "", ""
 	}
 
-	attackerUnits := []Unit{}
-	defenderUnits := []Unit{}
-	for _, unit := range rw.Attacker.Units {
-		if unit.Location == overlappingLocation {
-			attackerUnits = append(attackerUnits, unit)
-		}
-	}
-	for _, unit := range rw.Defender.Units {
-		if unit.Location == overlappingLocation {
-			defenderUnits = append(defenderUnits, unit)
-		}
-	}
-
+	contested := rw.Contested()
 	fmt.Printf("%s's units:\n", rw.Attacker.Username)
-	for _, unit := range attackerUnits {
+	for _, unit := range contested.Attacker.Units {
 		fmt.Printf("  * %v\n", unit.Rank)
 	}
 	fmt.Printf("%s's units:\n", rw.Defender.Username)
-	for _, unit := range defenderUnits {
+	for _, unit := range contested.Defender.Units {
 		fmt.Printf("  * %v\n", unit.Rank)
 	}
-	attackerPower := unitsToPowerLevel(attackerUnits)
-	defenderPower := unitsToPowerLevel(defenderUnits)
-	fmt.Printf("Attacker has a power level of %v\n", attackerPower)
-	fmt.Printf("Defender has a power level of %v\n", defenderPower)
-	if attackerPower > defenderPower {
-		fmt.Printf("%s has won the war!\n", rw.Attacker.Username)
-		if player.Username == rw.Defender.Username {
-			fmt.Println("You have lost the war!")
-			gs.removeUnitsInLocation(overlappingLocation)
-			fmt.Printf("Your units in %s have been killed.\n", overlappingLocation)
-			return WarOutcomeOpponentWon, rw.Attacker.Username, rw.Defender.Username
-		}
-		return WarOutcomeYouWon, rw.Attacker.Username, rw.Defender.Username
-	} else if defenderPower > attackerPower {
-		fmt.Printf("%s has won the war!\n", rw.Defender.Username)
-		if player.Username == rw.Attacker.Username {
-			fmt.Println("You have lost the war!")
-			gs.removeUnitsInLocation(overlappingLocation)
-			fmt.Printf("Your units in %s have been killed.\n", overlappingLocation)
-			return WarOutcomeOpponentWon, rw.Defender.Username, rw.Attacker.Username
+	fmt.Printf("Attacker has a power level of %v\n", armyPower(contested.Attacker))
+	fmt.Printf("Defender has a power level of %v\n", armyPower(contested.Defender))
+
+	winner, loser, draw := FightWar(rw)
+	if draw {
+		fmt.Println("The war ended in a draw!")
+		fmt.Printf("Your units in %s have been killed.\n", overlappingLocation)
+		gs.removeUnitsInLocation(overlappingLocation)
+		return WarOutcomeDraw, winner, loser
+	}
+	fmt.Printf("%s has won the war!\n", winner)
+	if player.Username == loser {
+		fmt.Println("You have lost the war!")
+		gs.removeUnitsInLocation(overlappingLocation)
+		fmt.Printf("Your units in %s have been killed.\n", overlappingLocation)
+		return WarOutcomeOpponentWon, winner, loser
+	}
+	return WarOutcomeYouWon, winner, loser
+}
+
+// Contested narrows rw down to the units each side has where they meet. That
+// is all a war is fought with, and publishing more would give away where the
+// rest of the defender's army is.
+func (rw RecognitionOfWar) Contested() RecognitionOfWar {
+	loc := getOverlappingLocation(rw.Attacker, rw.Defender)
+	return RecognitionOfWar{
+		Attacker: unitsIn(rw.Attacker, loc),
+		Defender: unitsIn(rw.Defender, loc),
+	}
+}
+
+func unitsIn(p Player, loc Location) Player {
+	army := Player{Username: p.Username, Units: map[int]Unit{}}
+	for id, unit := range p.Units {
+		if unit.Location == loc {
+			army.Units[id] = unit
 		}
-		return WarOutcomeYouWon, rw.Defender.Username, rw.Attacker.Username
 	}
-	fmt.Println("The war ended in a draw!")
-	fmt.Printf("Your units in %s have been killed.\n", overlappingLocation)
-	gs.removeUnitsInLocation(overlappingLocation)
-	return WarOutcomeDraw, rw.Attacker.Username, rw.Defender.Username
+	return army
+}
+
+// FightWar works out who wins rw from the power of the units each side has
+// where they meet. In a draw both sides lose their units there.
+func FightWar(rw RecognitionOfWar) (winner, loser string, draw bool) {
+	contested := rw.Contested()
+	attackerPower := armyPower(contested.Attacker)
+	defenderPower := armyPower(contested.Defender)
+	switch {
+	case attackerPower > defenderPower:
+		return rw.Attacker.Username, rw.Defender.Username, false
+	case defenderPower > attackerPower:
+		return rw.Defender.Username, rw.Attacker.Username, false
+	}
+	return rw.Attacker.Username, rw.Defender.Username, true
+}
+
+// armyPower is the power of all of p's units.
+func armyPower(p Player) int {
+	units := []Unit{}
+	for _, unit := range p.Units {
+		units = append(units, unit)
+	}
+	return unitsToPowerLevel(units)
 }
 
 func unitsToPowerLevel(units []Unit) int {
 	power := 0
 	for _, unit := range units {
+		power += unit.Veterancy
 		if unit.Rank == RankArtillery {
 			power += 10
 		}
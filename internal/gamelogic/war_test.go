@@ -0,0 +1,71 @@
+package gamelogic
+
+import "testing"
+
+func TestContested(t *testing.T) {
+	rw := RecognitionOfWar{
+		Attacker: Player{Username: "bob", Units: map[int]Unit{
+			1: {ID: 1, Rank: RankCavalry, Location: "asia"},
+			2: {ID: 2, Rank: RankInfantry, Location: "africa"},
+		}},
+		Defender: Player{Username: "alice", Units: map[int]Unit{
+			1: {ID: 1, Rank: RankInfantry, Location: "asia"},
+			2: {ID: 2, Rank: RankArtillery, Location: "europe"},
+			3: {ID: 3, Rank: RankInfantry, Location: "asia"},
+		}},
+	}
+	got := rw.Contested()
+	if got.Attacker.Username != "bob" || got.Defender.Username != "alice" {
+		t.Fatalf("sides = %s and %s, want bob and alice", got.Attacker.Username, got.Defender.Username)
+	}
+	if len(got.Attacker.Units) != 1 || got.Attacker.Units[1].Location != "asia" {
+		t.Errorf("attacker's units = %v, want only unit 1 in asia", got.Attacker.Units)
+	}
+	if len(got.Defender.Units) != 2 || got.Defender.Units[2].Rank != "" {
+		t.Errorf("defender's units = %v, want units 1 and 3 in asia", got.Defender.Units)
+	}
+	winner, loser, draw := FightWar(got)
+	if w, l, d := FightWar(rw); w != winner || l != loser || d != draw {
+		t.Errorf("narrowed war went to %s over %s (draw %v), the full one to %s over %s (draw %v)", winner, loser, draw, w, l, d)
+	}
+}
+
+func TestHandleWar(t *testing.T) {
+	army := func(username string, ranks ...UnitRank) Player {
+		p := Player{Username: username, Units: map[int]Unit{}}
+		for i, rank := range ranks {
+			p.Units[i+1] = Unit{ID: i + 1, Rank: rank, Location: "asia"}
+		}
+		return p
+	}
+	tests := []struct {
+		name     string
+		attacker Player
+		defender Player
+		outcome  WarOutcome
+		winner   string
+	}{
+		{"attacker wins", army("alice", RankCavalry), army("bob", RankInfantry), WarOutcomeYouWon, "alice"},
+		{"defender wins", army("alice", RankInfantry), army("bob", RankArtillery), WarOutcomeOpponentWon, "bob"},
+		{"draw", army("alice", RankCavalry), army("bob", RankInfantry, RankInfantry, RankInfantry, RankInfantry, RankInfantry), WarOutcomeDraw, "alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := NewGameState("alice")
+			for _, unit := range tt.attacker.Units {
+				gs.addUnit(unit)
+			}
+			rw := RecognitionOfWar{Attacker: tt.attacker, Defender: tt.defender}
+			outcome, winner, _ := gs.HandleWar(rw)
+			if outcome != tt.outcome || winner != tt.winner {
+				t.Errorf("HandleWar() = %v won by %s, want %v won by %s", outcome, winner, tt.outcome, tt.winner)
+			}
+			if fought, _, _ := FightWar(rw); fought != winner {
+				t.Errorf("FightWar() says %s won, HandleWar %s", fought, winner)
+			}
+			if survived := len(gs.GetPlayerSnap().Units) > 0; survived != (tt.outcome == WarOutcomeYouWon) {
+				t.Errorf("alice's units = %v after %s", gs.GetPlayerSnap().Units, tt.name)
+			}
+		})
+	}
+}
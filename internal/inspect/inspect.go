@@ -0,0 +1,184 @@
+// Package inspect decodes raw Peril messages off the wire, for tools that
+// see deliveries without knowing what's in them.
+package inspect
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+// Message is a decoded delivery.
+type Message struct {
+	Type  string
+	Value any
+}
+
+// topicTypes and directTypes map routing key prefixes to the types sent
+// with them. The same prefix can carry different types on each exchange.
+var topicTypes = map[string]func() any{
+	routing.ArmyMovesPrefix:       func() any { return &gamelogic.ArmyMove{} },
+	routing.WarRecognitionsPrefix: func() any { return &gamelogic.RecognitionOfWar{} },
+	routing.TurnOrdersKey:         func() any { return &routing.TurnOrders{} },
+	routing.GameLogSlug:           func() any { return &routing.GameLog{} },
+	routing.GameEventsPrefix:      func() any { return &gamelogic.GameEvent{} },
+	routing.RecordedEventsPrefix:  func() any { return &gamelogic.GameEvent{} },
+	routing.ChatPrefix:            func() any { return &routing.ChatMessage{} },
+	routing.PresencePrefix:        func() any { return &routing.Presence{} },
+	routing.PlayerJoinedPrefix:    func() any { return &routing.PlayerJoined{} },
+	routing.PlayerLeftPrefix:      func() any { return &routing.PlayerLeft{} },
+	routing.DiplomacyPrefix:       func() any { return &routing.DiplomacyRequest{} },
+}
+
+var directTypes = map[string]func() any{
+	routing.ArmyMovesPrefix:  func() any { return &gamelogic.ArmyMove{} },
+	routing.WarResultsPrefix: func() any { return &gamelogic.GameEvent{} },
+	routing.PauseKey:         func() any { return &routing.PlayingState{} },
+	routing.TurnKey:          func() any { return &routing.TurnStarted{} },
+	routing.TurnResolvedKey:  func() any { return &gamelogic.TurnResolved{} },
+	routing.GameOverKey:      func() any { return &routing.GameOver{} },
+	routing.EconomyPrefix:    func() any { return &routing.EconomyUpdate{} },
+	routing.AdminPrefix:      func() any { return &routing.AdminMessage{} },
+	routing.DiplomacyPrefix:  func() any { return &routing.DiplomacyUpdate{} },
+}
+
+// newValue returns a pointer to the type sent on exchange with key, or nil
+// if it isn't known.
+func newValue(exchange, key string) any {
+	prefix, rest, _ := strings.Cut(key, ".")
+	if exchange == routing.ExchangePerilDirect && prefix == routing.LobbyKey {
+		// requests go to the lobby, answers to lobby.<username>
+		if rest == "" {
+			return &routing.LobbyRequest{}
+		}
+		return &routing.LobbyResponse{}
+	}
+	types := topicTypes
+	if exchange == routing.ExchangePerilDirect {
+		types = directTypes
+	}
+	f, ok := types[prefix]
+	if !ok {
+		return nil
+	}
+	return f()
+}
+
+// Decode turns a delivery's body into the type sent with its routing key,
+// using its content type to pick the encoding. Unknown keys decode to a
+// generic value for JSON, and fail for gob.
+func Decode(exchange, key, contentType string, body []byte) (Message, error) {
+	v := newValue(exchange, key)
+	switch contentType {
+	case "application/json":
+		if v == nil {
+			var raw any
+			err := json.Unmarshal(body, &raw)
+			if err != nil {
+				return Message{}, fmt.Errorf("error decoding json: %w", err)
+			}
+			return Message{Type: "unknown", Value: raw}, nil
+		}
+		err := json.Unmarshal(body, v)
+		if err != nil {
+			return Message{}, fmt.Errorf("error decoding json: %w", err)
+		}
+	case "application/gob":
+		if v == nil {
+			return Message{}, fmt.Errorf("no known type for gob message with key %s", key)
+		}
+		err := gob.NewDecoder(bytes.NewReader(body)).Decode(v)
+		if err != nil {
+			return Message{}, fmt.Errorf("error decoding gob: %w", err)
+		}
+	default:
+		return Message{}, fmt.Errorf("unknown content type: %q", contentType)
+	}
+	return Message{Type: typeName(v), Value: reflect.ValueOf(v).Elem().Interface()}, nil
+}
+
+// typeName is the package qualified name of the type v points to, such as
+// gamelogic.ArmyMove.
+func typeName(v any) string {
+	t := reflect.TypeOf(v).Elem()
+	pkg := t.PkgPath()
+	return pkg[strings.LastIndex(pkg, "/")+1:] + "." + t.Name()
+}
+
+// Username picks the player out of a routing key, following the
+// <prefix>.<game>.<username> convention, or lobby.<username> for lobby
+// answers. Keys meant for the whole game have no username.
+func Username(key string) string {
+	parts := strings.Split(key, ".")
+	if parts[0] == routing.LobbyKey && len(parts) == 2 {
+		return parts[1]
+	}
+	if len(parts) >= 3 {
+		return parts[2]
+	}
+	return ""
+}
+
+// Users lists the players a message is about: the one in its routing key,
+// plus any named in its Username, From, To or Target fields, or in the
+// Player of a move.
+func Users(key string, m Message) []string {
+	users := []string{}
+	if u := Username(key); u != "" {
+		users = append(users, u)
+	}
+	v := reflect.ValueOf(m.Value)
+	if v.Kind() != reflect.Struct {
+		return users
+	}
+	for _, name := range []string{"Username", "From", "To", "Target"} {
+		f := v.FieldByName(name)
+		if f.IsValid() && f.Kind() == reflect.String && f.String() != "" {
+			users = append(users, f.String())
+		}
+	}
+	if move, ok := m.Value.(gamelogic.ArmyMove); ok {
+		users = append(users, move.Player.Username)
+	}
+	return users
+}
+
+// DirectKeys lists the peril_direct routing keys used by games and users. A
+// direct exchange only delivers exact key matches, so this is as close as a
+// tool can get to binding everything on it.
+func DirectKeys(games, users []string) []string {
+	keys := []string{routing.LobbyKey}
+	for _, u := range users {
+		keys = append(keys, routing.Key(routing.LobbyKey, u))
+	}
+	for _, g := range games {
+		for _, prefix := range []string{
+			routing.PauseKey,
+			routing.TurnKey,
+			routing.TurnResolvedKey,
+			routing.GameOverKey,
+			routing.AdminPrefix,
+			routing.WarResultsPrefix,
+			routing.DiplomacyPrefix,
+		} {
+			keys = append(keys, routing.Key(prefix, g))
+		}
+		for _, u := range users {
+			for _, prefix := range []string{
+				routing.ArmyMovesPrefix,
+				routing.EconomyPrefix,
+				routing.AdminPrefix,
+				routing.DiplomacyPrefix,
+			} {
+				keys = append(keys, routing.Key(prefix, g, u))
+			}
+		}
+	}
+	return keys
+}
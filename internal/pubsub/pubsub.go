@@ -8,8 +8,14 @@ import (
 	"fmt"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
 )
 
+// Prefetch is how many unacked deliveries each subscription is sent at once.
+// It can be changed through the config package.
+var Prefetch = 10
+
 type SimpleQueueType int
 type AckType int
 
@@ -24,7 +30,19 @@ const (
 	NackDiscard
 )
 
-func PublishJSON[T any](ch *amqp.Channel, exchange, key string, val T) error {
+// Publisher is where messages are published: an *amqp.Channel, or an
+// in-process broker such as recording.Broker.
+type Publisher interface {
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+}
+
+// Consumer binds queues and delivers what is routed to them. AMQPConsumer
+// is the real broker; recording.Broker is an in-process one.
+type Consumer interface {
+	Consume(exchange, queueName, key string, queueType SimpleQueueType) (<-chan amqp.Delivery, error)
+}
+
+func PublishJSON[T any](ch Publisher, exchange, key string, val T) error {
 	jsonVal, err := json.Marshal(val)
 	if err != nil {
 		return fmt.Errorf("error marshalling %v to JSON: %w", val, err)
@@ -47,7 +65,7 @@ func PublishJSON[T any](ch *amqp.Channel, exchange, key string, val T) error {
 	return nil
 }
 
-func PublishGob[T any](ch *amqp.Channel, exchange, key string, val T) error {
+func PublishGob[T any](ch Publisher, exchange, key string, val T) error {
 	var buf bytes.Buffer
 	enc := gob.NewEncoder(&buf)
 	err := enc.Encode(val)
@@ -100,7 +118,7 @@ func DeclareAndBind(
 		noWait = false
 	}
 	args := make(amqp.Table)
-	args["x-dead-letter-exchange"] = "peril_dlx"
+	args["x-dead-letter-exchange"] = routing.ExchangePerilDLX
 	q, err := channel.QueueDeclare(queueName, durable, autoDelete, exclusive, noWait, args)
 	if err != nil {
 		return nil, amqp.Queue{}, fmt.Errorf("error creating queue: %w", err)
@@ -113,6 +131,32 @@ func DeclareAndBind(
 	return channel, q, nil
 }
 
+type amqpConsumer struct {
+	conn *amqp.Connection
+}
+
+// AMQPConsumer consumes from the broker conn is connected to. Each queue
+// gets its own channel.
+func AMQPConsumer(conn *amqp.Connection) Consumer {
+	return amqpConsumer{conn: conn}
+}
+
+func (c amqpConsumer) Consume(exchange, queueName, key string, queueType SimpleQueueType) (<-chan amqp.Delivery, error) {
+	channel, queue, err := DeclareAndBind(c.conn, exchange, queueName, key, queueType)
+	if err != nil {
+		return nil, fmt.Errorf("error declaring or binding queue: %w", err)
+	}
+	err = channel.Qos(Prefetch, 0, false)
+	if err != nil {
+		return nil, fmt.Errorf("error trying to set QoS: %w", err)
+	}
+	deliveryChan, err := channel.Consume(queue.Name, "", false, false, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error while calling Consume: %w", err)
+	}
+	return deliveryChan, nil
+}
+
 func SubscribeJSON[T any](
 	conn *amqp.Connection,
 	exchange,
@@ -120,6 +164,29 @@ func SubscribeJSON[T any](
 	key string,
 	queueType SimpleQueueType, // an enum to represent "durable" or "transient"
 	handler func(T) AckType,
+) error {
+	return SubscribeJSONFrom(AMQPConsumer(conn), exchange, queueName, key, queueType, handler)
+}
+
+func SubscribeGob[T any](
+	conn *amqp.Connection,
+	exchange,
+	queueName,
+	key string,
+	queueType SimpleQueueType, // an enum to represent "durable" or "transient"
+	handler func(T) AckType,
+) error {
+	return SubscribeGobFrom(AMQPConsumer(conn), exchange, queueName, key, queueType, handler)
+}
+
+// SubscribeJSONFrom is SubscribeJSON for any Consumer.
+func SubscribeJSONFrom[T any](
+	c Consumer,
+	exchange,
+	queueName,
+	key string,
+	queueType SimpleQueueType,
+	handler func(T) AckType,
 ) error {
 	u := func(data []byte) (T, error) {
 		var body T
@@ -129,15 +196,16 @@ func SubscribeJSON[T any](
 		}
 		return body, nil
 	}
-	return subscribe(conn, exchange, queueName, key, queueType, handler, u)
+	return subscribe(c, exchange, queueName, key, queueType, handler, u)
 }
 
-func SubscribeGob[T any](
-	conn *amqp.Connection,
+// SubscribeGobFrom is SubscribeGob for any Consumer.
+func SubscribeGobFrom[T any](
+	c Consumer,
 	exchange,
 	queueName,
 	key string,
-	queueType SimpleQueueType, // an enum to represent "durable" or "transient"
+	queueType SimpleQueueType,
 	handler func(T) AckType,
 ) error {
 	u := func(data []byte) (T, error) {
@@ -149,11 +217,11 @@ func SubscribeGob[T any](
 		}
 		return body, nil
 	}
-	return subscribe(conn, exchange, queueName, key, queueType, handler, u)
+	return subscribe(c, exchange, queueName, key, queueType, handler, u)
 }
 
 func subscribe[T any](
-	conn *amqp.Connection,
+	c Consumer,
 	exchange,
 	queueName,
 	key string,
@@ -161,17 +229,9 @@ func subscribe[T any](
 	handler func(T) AckType,
 	unmarshaller func([]byte) (T, error),
 ) error {
-	channel, queue, err := DeclareAndBind(conn, exchange, queueName, key, queueType)
-	if err != nil {
-		return fmt.Errorf("error declaring or binding queue: %w", err)
-	}
-	err = channel.Qos(10, 0, false)
-	if err != nil {
-		return fmt.Errorf("error trying to set QoS: %w", err)
-	}
-	deliveryChan, err := channel.Consume(queue.Name, "", false, false, false, false, nil)
+	deliveryChan, err := c.Consume(exchange, queueName, key, queueType)
 	if err != nil {
-		return fmt.Errorf("error while calling Consume: %w", err)
+		return err
 	}
 
 	go func() {
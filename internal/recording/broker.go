@@ -0,0 +1,165 @@
+package recording
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+// QueueSize is how many deliveries a Broker queue buffers. Publishing to a
+// full queue waits for it to be read, or for the context to be done.
+const QueueSize = 1024
+
+// Broker is a tiny in-process stand-in for RabbitMQ with the Peril
+// exchanges. Publishing routes each message to its bound queues before
+// returning, so a replay through it always delivers in the same order. It
+// is a pubsub.Publisher and a pubsub.Consumer, so anything subscribed with
+// the pubsub package can run against it. Acks are ignored: nothing is
+// requeued or dead-lettered.
+type Broker struct {
+	mu       sync.Mutex
+	kinds    map[string]string
+	bindings []binding
+	queues   map[string]chan amqp.Delivery
+	// closed is closed by Close, which waits for the publishes in sending
+	// to give up before closing the queues
+	closed  chan struct{}
+	sending sync.WaitGroup
+}
+
+type binding struct {
+	queue    string
+	exchange string
+	key      string
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		kinds: map[string]string{
+			routing.ExchangePerilDirect: amqp.ExchangeDirect,
+			routing.ExchangePerilTopic:  amqp.ExchangeTopic,
+		},
+		queues: map[string]chan amqp.Delivery{},
+		closed: make(chan struct{}),
+	}
+}
+
+// Bind binds queue to exchange with key, creating the queue if needed, and
+// returns its deliveries.
+func (b *Broker) Bind(queue, exchange, key string) (<-chan amqp.Delivery, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.kinds[exchange]; !ok {
+		return nil, fmt.Errorf("no exchange named %s", exchange)
+	}
+	q, ok := b.queues[queue]
+	if !ok {
+		q = make(chan amqp.Delivery, QueueSize)
+		b.queues[queue] = q
+	}
+	b.bindings = append(b.bindings, binding{queue: queue, exchange: exchange, key: key})
+	return q, nil
+}
+
+// Consume binds queue to exchange with key like Bind. Every queue behaves
+// the same whatever its type.
+func (b *Broker) Consume(exchange, queue, key string, _ pubsub.SimpleQueueType) (<-chan amqp.Delivery, error) {
+	return b.Bind(queue, exchange, key)
+}
+
+// PublishWithContext routes msg to the queues bound to it. The queues are
+// looked up under the lock but sent to without it, so a full queue only
+// holds up this publish, not the rest of the broker.
+func (b *Broker) PublishWithContext(ctx context.Context, exchange, key string, _, _ bool, msg amqp.Publishing) error {
+	b.mu.Lock()
+	kind, ok := b.kinds[exchange]
+	if !ok {
+		b.mu.Unlock()
+		return fmt.Errorf("no exchange named %s", exchange)
+	}
+	select {
+	case <-b.closed:
+		// nothing is bound any more
+		b.mu.Unlock()
+		return nil
+	default:
+	}
+	routed := map[string]bool{}
+	targets := []chan amqp.Delivery{}
+	for _, bd := range b.bindings {
+		if bd.exchange != exchange || routed[bd.queue] {
+			continue
+		}
+		if kind == amqp.ExchangeDirect && bd.key != key {
+			continue
+		}
+		if kind == amqp.ExchangeTopic && !routing.MatchTopic(bd.key, key) {
+			continue
+		}
+		routed[bd.queue] = true
+		targets = append(targets, b.queues[bd.queue])
+	}
+	b.sending.Add(1)
+	b.mu.Unlock()
+	defer b.sending.Done()
+
+	d := delivery(exchange, key, msg)
+	for _, q := range targets {
+		select {
+		case q <- d:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-b.closed:
+			return fmt.Errorf("broker closed while publishing to %s", key)
+		}
+	}
+	return nil
+}
+
+// Close closes every queue, ending the loops reading from them.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	select {
+	case <-b.closed:
+		b.mu.Unlock()
+		return
+	default:
+	}
+	close(b.closed)
+	b.mu.Unlock()
+	b.sending.Wait()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for name, q := range b.queues {
+		close(q)
+		delete(b.queues, name)
+	}
+	b.bindings = nil
+}
+
+func delivery(exchange, key string, msg amqp.Publishing) amqp.Delivery {
+	return amqp.Delivery{
+		Exchange:        exchange,
+		RoutingKey:      key,
+		ContentType:     msg.ContentType,
+		ContentEncoding: msg.ContentEncoding,
+		DeliveryMode:    msg.DeliveryMode,
+		Priority:        msg.Priority,
+		CorrelationId:   msg.CorrelationId,
+		ReplyTo:         msg.ReplyTo,
+		Expiration:      msg.Expiration,
+		MessageId:       msg.MessageId,
+		Timestamp:       msg.Timestamp,
+		Type:            msg.Type,
+		UserId:          msg.UserId,
+		AppId:           msg.AppId,
+		Headers:         msg.Headers,
+		Body:            msg.Body,
+	}
+}
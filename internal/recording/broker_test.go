@@ -0,0 +1,185 @@
+package recording
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/routing"
+)
+
+// received drains what is waiting in q.
+func received(q <-chan amqp.Delivery) []string {
+	keys := []string{}
+	for {
+		select {
+		case d := <-q:
+			keys = append(keys, d.RoutingKey)
+		default:
+			return keys
+		}
+	}
+}
+
+func TestBrokerRouting(t *testing.T) {
+	b := NewBroker()
+	defer b.Close()
+	bind := func(queue, exchange, key string) <-chan amqp.Delivery {
+		q, err := b.Bind(queue, exchange, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return q
+	}
+	pause := bind("pause", routing.ExchangePerilDirect, "pause.main")
+	logs := bind("logs", routing.ExchangePerilTopic, "game_logs.main.*")
+	// two bindings that both match still deliver once
+	all := bind("all", routing.ExchangePerilTopic, "#")
+	bind("all", routing.ExchangePerilTopic, "game_logs.#")
+
+	publish := []struct{ exchange, key string }{
+		{routing.ExchangePerilDirect, "pause.main"},
+		{routing.ExchangePerilDirect, "pause.other"},
+		{routing.ExchangePerilTopic, "game_logs.main.alice"},
+		{routing.ExchangePerilTopic, "game_logs.other.bob"},
+		{routing.ExchangePerilTopic, "pause.main"},
+	}
+	for _, p := range publish {
+		err := b.PublishWithContext(context.Background(), p.exchange, p.key, false, false, amqp.Publishing{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		q    <-chan amqp.Delivery
+		want []string
+	}{
+		{"direct", pause, []string{"pause.main"}},
+		{"topic", logs, []string{"game_logs.main.alice"}},
+		{"catch-all", all, []string{"game_logs.main.alice", "game_logs.other.bob", "pause.main"}},
+	}
+	for _, tt := range tests {
+		got := received(tt.q)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+
+	err := b.PublishWithContext(context.Background(), "nowhere", "key", false, false, amqp.Publishing{})
+	if err == nil {
+		t.Error("publishing to an unknown exchange succeeded")
+	}
+	_, err = b.Bind("q", "nowhere", "key")
+	if err == nil {
+		t.Error("binding to an unknown exchange succeeded")
+	}
+}
+
+// TestBrokerPubSub runs the pubsub package against the broker, as a client
+// or server would.
+func TestBrokerPubSub(t *testing.T) {
+	b := NewBroker()
+	defer b.Close()
+
+	got := make(chan routing.PlayingState, 1)
+	err := pubsub.SubscribeJSONFrom(b, routing.ExchangePerilDirect, "pause.main.alice", "pause.main", pubsub.QueueTypeTransient,
+		func(ps routing.PlayingState) pubsub.AckType {
+			got <- ps
+			return pubsub.Ack
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := make(chan routing.GameLog, 1)
+	err = pubsub.SubscribeGobFrom(b, routing.ExchangePerilTopic, "game_logs", "game_logs.*.*", pubsub.QueueTypeDurable,
+		func(gl routing.GameLog) pubsub.AckType {
+			logs <- gl
+			return pubsub.Ack
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pubsub.PublishJSON(b, routing.ExchangePerilDirect, "pause.main", routing.PlayingState{IsPaused: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pubsub.PublishGob(b, routing.ExchangePerilTopic, "game_logs.main.alice", routing.GameLog{Username: "alice", Message: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ps := <-got:
+		if !ps.IsPaused {
+			t.Errorf("got %+v, want paused", ps)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the JSON subscription got nothing")
+	}
+	select {
+	case gl := <-logs:
+		if gl.Username != "alice" || gl.Message != "hi" {
+			t.Errorf("got %+v, want alice's log", gl)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the gob subscription got nothing")
+	}
+}
+
+// TestBrokerFullQueue checks a publish stuck on a full queue gives up with
+// its context and doesn't hold up the rest of the broker meanwhile.
+func TestBrokerFullQueue(t *testing.T) {
+	b := NewBroker()
+	defer b.Close()
+	_, err := b.Bind("full", routing.ExchangePerilTopic, "game_logs.#")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < QueueSize; i++ {
+		err = b.PublishWithContext(context.Background(), routing.ExchangePerilTopic, "game_logs.main.alice", false, false, amqp.Publishing{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- b.PublishWithContext(ctx, routing.ExchangePerilTopic, "game_logs.main.alice", false, false, amqp.Publishing{})
+	}()
+
+	pause, err := b.Bind("pause", routing.ExchangePerilDirect, "pause.main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.PublishWithContext(context.Background(), routing.ExchangePerilDirect, "pause.main", false, false, amqp.Publishing{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := received(pause); len(got) != 1 {
+		t.Errorf("pause got %v while another queue was full, want one delivery", got)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("publishing to a full queue succeeded")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("publishing to a full queue didn't give up when its context was cancelled")
+	}
+}
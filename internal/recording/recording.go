@@ -0,0 +1,170 @@
+// Package recording captures broker traffic to a file and plays it back, so
+// a session can be reproduced exactly against new builds.
+package recording
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/bikefrivolously/boot.dev-learn-pub-sub-starter/internal/pubsub"
+)
+
+// Entry is one recorded delivery. Offset is measured on the monotonic clock
+// from the start of the recording.
+type Entry struct {
+	Offset     time.Duration
+	Exchange   string
+	RoutingKey string
+	Properties Properties
+	Body       []byte
+}
+
+// Properties are the AMQP message properties worth keeping.
+type Properties struct {
+	ContentType     string     `json:",omitempty"`
+	ContentEncoding string     `json:",omitempty"`
+	DeliveryMode    uint8      `json:",omitempty"`
+	Priority        uint8      `json:",omitempty"`
+	CorrelationId   string     `json:",omitempty"`
+	ReplyTo         string     `json:",omitempty"`
+	Expiration      string     `json:",omitempty"`
+	MessageId       string     `json:",omitempty"`
+	Timestamp       time.Time  `json:",omitempty"`
+	Type            string     `json:",omitempty"`
+	UserId          string     `json:",omitempty"`
+	AppId           string     `json:",omitempty"`
+	Headers         amqp.Table `json:",omitempty"`
+}
+
+func (e Entry) Publishing() amqp.Publishing {
+	p := e.Properties
+	return amqp.Publishing{
+		ContentType:     p.ContentType,
+		ContentEncoding: p.ContentEncoding,
+		DeliveryMode:    p.DeliveryMode,
+		Priority:        p.Priority,
+		CorrelationId:   p.CorrelationId,
+		ReplyTo:         p.ReplyTo,
+		Expiration:      p.Expiration,
+		MessageId:       p.MessageId,
+		Timestamp:       p.Timestamp,
+		Type:            p.Type,
+		UserId:          p.UserId,
+		AppId:           p.AppId,
+		Headers:         p.Headers,
+		Body:            e.Body,
+	}
+}
+
+// Recorder writes deliveries to w as JSON lines.
+type Recorder struct {
+	mu    sync.Mutex
+	enc   *json.Encoder
+	start time.Time
+	count int
+}
+
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{
+		enc:   json.NewEncoder(w),
+		start: time.Now(),
+	}
+}
+
+func (r *Recorder) Record(d amqp.Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := Entry{
+		Offset:     time.Since(r.start),
+		Exchange:   d.Exchange,
+		RoutingKey: d.RoutingKey,
+		Properties: Properties{
+			ContentType:     d.ContentType,
+			ContentEncoding: d.ContentEncoding,
+			DeliveryMode:    d.DeliveryMode,
+			Priority:        d.Priority,
+			CorrelationId:   d.CorrelationId,
+			ReplyTo:         d.ReplyTo,
+			Expiration:      d.Expiration,
+			MessageId:       d.MessageId,
+			Timestamp:       d.Timestamp,
+			Type:            d.Type,
+			UserId:          d.UserId,
+			AppId:           d.AppId,
+			Headers:         d.Headers,
+		},
+		Body: d.Body,
+	}
+	err := r.enc.Encode(e)
+	if err != nil {
+		return fmt.Errorf("could not write entry: %v", err)
+	}
+	r.count++
+	return nil
+}
+
+// Count is the number of deliveries recorded so far.
+func (r *Recorder) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.count
+}
+
+// Load reads a recording written by a Recorder.
+func Load(r io.Reader) ([]Entry, error) {
+	entries := []Entry{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var e Entry
+		err := json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse entry on line %d: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read recording: %v", err)
+	}
+	return entries, nil
+}
+
+// ErrStopped is returned by Replay when it's stopped before the end.
+var ErrStopped = errors.New("replay stopped")
+
+// Replay publishes entries in order to a real broker's channel or a Broker,
+// keeping the gaps between them divided by speed, so 2 plays twice as fast.
+// A speed of 0 sends everything as fast as possible.
+func Replay(entries []Entry, p pubsub.Publisher, speed float64, stop <-chan struct{}) error {
+	start := time.Now()
+	for i, e := range entries {
+		if speed > 0 {
+			wait := time.Duration(float64(e.Offset)/speed) - time.Since(start)
+			if wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-stop:
+					return ErrStopped
+				}
+			}
+		}
+		select {
+		case <-stop:
+			return ErrStopped
+		default:
+		}
+		err := p.PublishWithContext(context.Background(), e.Exchange, e.RoutingKey, false, false, e.Publishing())
+		if err != nil {
+			return fmt.Errorf("error publishing entry %d (%s %s): %w", i, e.Exchange, e.RoutingKey, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestRecordLoad(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(&buf)
+	deliveries := []amqp.Delivery{
+		{Exchange: "peril_topic", RoutingKey: "game_logs.main.alice", ContentType: "application/gob", Body: []byte{1, 2, 3}},
+		{Exchange: "peril_direct", RoutingKey: "pause.main", ContentType: "application/json", Body: []byte(`{"IsPaused":true}`),
+			Headers: amqp.Table{"x-test": "yes"}},
+	}
+	for _, d := range deliveries {
+		err := r.Record(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r.Count() != len(deliveries) {
+		t.Errorf("Count() = %d, want %d", r.Count(), len(deliveries))
+	}
+
+	entries, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(deliveries) {
+		t.Fatalf("loaded %d entries, want %d", len(entries), len(deliveries))
+	}
+	for i, e := range entries {
+		d := deliveries[i]
+		p := e.Publishing()
+		if e.Exchange != d.Exchange || e.RoutingKey != d.RoutingKey || p.ContentType != d.ContentType || !bytes.Equal(p.Body, d.Body) {
+			t.Errorf("entry %d = %+v, want %+v", i, e, d)
+		}
+	}
+	if entries[1].Properties.Headers["x-test"] != "yes" {
+		t.Errorf("headers = %v, want x-test kept", entries[1].Properties.Headers)
+	}
+	if entries[1].Offset < entries[0].Offset {
+		t.Errorf("offsets go backwards: %v then %v", entries[0].Offset, entries[1].Offset)
+	}
+}
+
+func TestLoadMalformed(t *testing.T) {
+	_, err := Load(strings.NewReader(`{"Exchange":"peril_topic"}` + "\nnot json\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Load() error = %v, want one about line 2", err)
+	}
+}
+
+// clock records when each message was published, from the start of the
+// replay.
+type clock struct {
+	mu    sync.Mutex
+	start time.Time
+	at    []time.Duration
+	keys  []string
+}
+
+func (c *clock) PublishWithContext(_ context.Context, _, key string, _, _ bool, _ amqp.Publishing) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.at = append(c.at, time.Since(c.start))
+	c.keys = append(c.keys, key)
+	return nil
+}
+
+func entriesEvery(gap time.Duration, n int) []Entry {
+	entries := []Entry{}
+	for i := 0; i < n; i++ {
+		entries = append(entries, Entry{
+			Offset:     time.Duration(i) * gap,
+			Exchange:   "peril_topic",
+			RoutingKey: string(rune('a' + i)),
+		})
+	}
+	return entries
+}
+
+func TestReplaySpeed(t *testing.T) {
+	const gap = 100 * time.Millisecond
+	tests := []struct {
+		speed    float64
+		min, max time.Duration
+	}{
+		// as fast as possible
+		{speed: 0, min: 0, max: gap / 2},
+		// twice as fast: the last of three entries, 200ms in, goes at 100ms
+		{speed: 2, min: gap, max: 2*gap - 10*time.Millisecond},
+	}
+	for _, tt := range tests {
+		c := &clock{start: time.Now()}
+		err := Replay(entriesEvery(gap, 3), c, tt.speed, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(c.keys, "") != "abc" {
+			t.Errorf("speed %v: published %v, want a, b and c in order", tt.speed, c.keys)
+		}
+		last := c.at[len(c.at)-1]
+		if last < tt.min || last > tt.max {
+			t.Errorf("speed %v: last entry published at %v, want between %v and %v", tt.speed, last, tt.min, tt.max)
+		}
+	}
+}
+
+func TestReplayStop(t *testing.T) {
+	stop := make(chan struct{})
+	c := &clock{start: time.Now()}
+	time.AfterFunc(50*time.Millisecond, func() { close(stop) })
+	err := Replay(entriesEvery(time.Second, 3), c, 1, stop)
+	if !errors.Is(err, ErrStopped) {
+		t.Errorf("Replay() = %v, want ErrStopped", err)
+	}
+	if len(c.keys) != 1 {
+		t.Errorf("published %v before stopping, want just the first entry", c.keys)
+	}
+}
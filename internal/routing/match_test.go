@@ -0,0 +1,45 @@
+package routing
+
+import "testing"
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern, key string
+		want         bool
+	}{
+		{"game_logs.main.alice", "game_logs.main.alice", true},
+		{"game_logs.main.alice", "game_logs.main.bob", false},
+
+		{"game_logs.*.alice", "game_logs.main.alice", true},
+		{"game_logs.*", "game_logs.main.alice", false},
+		{"game_logs.*.*", "game_logs.main", false},
+		{"*", "game_logs", true},
+
+		{"#", "game_logs.main.alice", true},
+		{"#.alice", "game_logs.main.alice", true},
+		{"#.alice", "alice", true},
+		{"#.alice", "game_logs.main.bob", false},
+		{"game_logs.#.alice", "game_logs.main.alice", true},
+		{"game_logs.#.alice", "game_logs.a.b.alice", true},
+		{"game_logs.#.alice", "game_logs.alice", true},
+		{"game_logs.#.alice", "game_logs.main.bob", false},
+		{"game_logs.#", "game_logs.main.alice", true},
+		{"game_logs.#", "game_logs", true},
+		{"game_logs.#", "army_moves.main", false},
+		{"#.*", "game_logs", true},
+		{"#.*", "", true},
+
+		// an empty key is a single empty word, as it is to RabbitMQ
+		{"#", "", true},
+		{"*", "", true},
+		{"game_logs", "", false},
+		{"", "", true},
+		{"", "game_logs", false},
+	}
+	for _, tt := range tests {
+		got := MatchTopic(tt.pattern, tt.key)
+		if got != tt.want {
+			t.Errorf("MatchTopic(%q, %q) = %v, want %v", tt.pattern, tt.key, got, tt.want)
+		}
+	}
+}
@@ -10,4 +10,187 @@ type GameLog struct {
 	CurrentTime time.Time
 	Message     string
 	Username    string
+	Game        string
+}
+
+type TurnStarted struct {
+	Number   int
+	Deadline time.Time
+}
+
+type TurnOrders struct {
+	Game     string
+	Number   int
+	Username string
+	Orders   [][]string
+}
+
+// EconomyUpdate is the server's view of a player's balance. When a spawn is
+// refused, RejectedUnitID names the unit to take back.
+type EconomyUpdate struct {
+	Balance        int
+	Income         int
+	RejectedUnitID int
+	Reason         string
+}
+
+type Standing struct {
+	Username    string
+	Score       int
+	Units       int
+	Territories int
+}
+
+// GameOver announces the end of a game. Winner is empty when the game ended
+// without one.
+type GameOver struct {
+	Winner    string
+	Reason    string
+	Standings []Standing
+	Time      time.Time
+}
+
+const (
+	LobbyList   = "list"
+	LobbyCreate = "create"
+	LobbyJoin   = "join"
+	LobbyLeave  = "leave"
+)
+
+// LobbyRequest is sent by a client to the server's lobby. Game is ignored
+// for list requests, and Fog is only used when creating a game.
+type LobbyRequest struct {
+	Username string
+	Action   string
+	Game     string
+	Fog      bool
+}
+
+// LobbyResponse answers a LobbyRequest on the requesting user's lobby key.
+type LobbyResponse struct {
+	Action string
+	Game   string
+	Games  []GameInfo
+	Error  string
+}
+
+type GameInfo struct {
+	ID      string
+	Players []string
+	Paused  bool
+	Over    bool
+	// Fog games send each player only the moves near their own units.
+	Fog bool
+}
+
+const (
+	PresenceJoin      = "join"
+	PresenceHeartbeat = "heartbeat"
+	PresenceLeave     = "leave"
+)
+
+const (
+	HeartbeatInterval = 5 * time.Second
+	// HeartbeatTimeout is how long the server waits without a heartbeat
+	// before it treats a player as gone.
+	HeartbeatTimeout = 3 * HeartbeatInterval
+)
+
+// Presence is published by clients when they join or leave a game, and
+// every HeartbeatInterval while they're in it.
+type Presence struct {
+	Game     string
+	Username string
+	Status   string
+	Time     time.Time
+}
+
+type PlayerJoined struct {
+	Game     string
+	Username string
+	Time     time.Time
+}
+
+type PlayerLeft struct {
+	Game     string
+	Username string
+	Reason   string
+	Time     time.Time
+}
+
+const (
+	AdminKick      = "kick"
+	AdminReset     = "reset"
+	AdminBroadcast = "broadcast"
+)
+
+// AdminMessage is an operator action sent by the server to every client in a
+// game. Target is only set for kicks.
+type AdminMessage struct {
+	Game    string
+	Kind    string
+	Target  string
+	Message string
+	Time    time.Time
+}
+
+// ChatMessage is a chat line between players. To is empty for messages to
+// everyone in the game.
+type ChatMessage struct {
+	Game    string
+	From    string
+	To      string
+	Message string
+	Time    time.Time
+}
+
+const (
+	TreatyAlliance  = "alliance"
+	TreatyCeasefire = "ceasefire"
+)
+
+// Treaty is a peace agreement between two players. Ceasefires end at
+// Expires; alliances have a zero Expires and last until one side breaks them.
+type Treaty struct {
+	Kind     string
+	Proposer string
+	Partner  string
+	Duration time.Duration
+	Expires  time.Time
+}
+
+const (
+	DiplomacyPropose = "propose"
+	DiplomacyAccept  = "accept"
+	DiplomacyBreak   = "break"
+)
+
+// DiplomacyRequest is sent by a client to the server, which decides whether
+// it is allowed. For accept, Target is the player whose proposal is accepted.
+type DiplomacyRequest struct {
+	Game     string
+	From     string
+	Action   string
+	Kind     string
+	Target   string
+	Duration time.Duration
+}
+
+const (
+	DiplomacyProposed = "proposed"
+	DiplomacyAccepted = "accepted"
+	DiplomacyBroken   = "broken"
+	DiplomacyExpired  = "expired"
+	DiplomacyRejected = "rejected"
+)
+
+// DiplomacyUpdate is the server's answer. Updates sent to a single player
+// carry their full list of treaties in Treaties, which replaces whatever the
+// client had before.
+type DiplomacyUpdate struct {
+	Game     string
+	Event    string
+	Treaty   Treaty
+	Reason   string
+	Treaties []Treaty
 }
@@ -1,16 +1,84 @@
 package routing
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
 const (
 	ArmyMovesPrefix = "army_moves"
 
 	WarRecognitionsPrefix = "war"
+	WarResultsPrefix      = "war_results"
 
 	PauseKey = "pause"
 
+	TurnKey         = "turn"
+	TurnOrdersKey   = "turn_orders"
+	TurnResolvedKey = "turn_resolved"
+
+	GameOverKey = "game_over"
+
 	GameLogSlug = "game_logs"
+
+	GameEventsPrefix = "game_events"
+	// RecordedEventsPrefix keys are recorded_events.<game>: the game events
+	// the server has checked and recorded, in the order it recorded them.
+	RecordedEventsPrefix = "recorded_events"
+
+	EconomyPrefix = "economy"
+
+	LobbyKey = "lobby"
+
+	AdminPrefix = "admin"
+
+	// ChatPrefix keys are chat.<game> for everyone in a game and
+	// chat.<game>.<username> for whispers.
+	ChatPrefix = "chat"
+
+	DiplomacyPrefix = "diplomacy"
+
+	PresencePrefix     = "presence"
+	PlayerJoinedPrefix = "player_joined"
+	PlayerLeftPrefix   = "player_left"
 )
 
-const (
+const DefaultGame = "default"
+
+// The exchange and queue names can be changed through the config package.
+var (
 	ExchangePerilDirect = "peril_direct"
 	ExchangePerilTopic  = "peril_topic"
+	// ExchangePerilDLX gets every message rejected without requeueing, and
+	// sends it on to DeadLetterQueue.
+	ExchangePerilDLX = "peril_dlx"
+	DeadLetterQueue  = "peril_dlq"
 )
+
+// Key joins the parts of a routing key or queue name. Everything that
+// belongs to a game has the game ID right after the prefix, e.g.
+// Key(ArmyMovesPrefix, game, username) or Key(ArmyMovesPrefix, game, "*").
+func Key(parts ...string) string {
+	return strings.Join(parts, ".")
+}
+
+// Usernames and game IDs end up in routing keys, and game IDs in file names,
+// so they can't have dots, wildcards or slashes.
+var validName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+
+// ValidateUsername reports why username can't be used, if it can't.
+func ValidateUsername(username string) error {
+	if !validName.MatchString(username) {
+		return fmt.Errorf("%q is not a valid username, use letters, digits, - and _", username)
+	}
+	return nil
+}
+
+// ValidateGameID reports why id can't be used for a game, if it can't.
+func ValidateGameID(id string) error {
+	if !validName.MatchString(id) {
+		return fmt.Errorf("%q is not a valid game ID, use letters, digits, - and _", id)
+	}
+	return nil
+}
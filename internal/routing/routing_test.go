@@ -0,0 +1,35 @@
+package routing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateUsername(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"alice", true},
+		{"bot-1", true},
+		{"Big_Bob", true},
+		{strings.Repeat("a", 32), true},
+		{"", false},
+		{strings.Repeat("a", 33), false},
+		{"alice.bob", false},
+		{"*", false},
+		{"#", false},
+		{"al ice", false},
+		{"../alice", false},
+	}
+	for _, tt := range tests {
+		err := ValidateUsername(tt.name)
+		if (err == nil) != tt.want {
+			t.Errorf("ValidateUsername(%q) = %v, want ok %v", tt.name, err, tt.want)
+		}
+		err = ValidateGameID(tt.name)
+		if (err == nil) != tt.want {
+			t.Errorf("ValidateGameID(%q) = %v, want ok %v", tt.name, err, tt.want)
+		}
+	}
+}